@@ -1,21 +1,29 @@
 package abcrss
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"io"
 	"log"
-	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // RSS defines the structure of the RSS feed.
 type RSS struct {
-	XMLName xml.Name `xml:"rss"`
-	Version string   `xml:"version,attr"`
-	Channel Channel  `xml:"channel"`
+	XMLName      xml.Name `xml:"rss"`
+	Version      string   `xml:"version,attr"`
+	XMLNSMedia   string   `xml:"xmlns:media,attr,omitempty"`
+	XMLNSITunes  string   `xml:"xmlns:itunes,attr,omitempty"`
+	XMLNSPodcast string   `xml:"xmlns:podcast,attr,omitempty"`
+	XMLNSAtom    string   `xml:"xmlns:atom,attr,omitempty"`
+	XMLNSContent string   `xml:"xmlns:content,attr,omitempty"`
+	Channel      Channel  `xml:"channel"`
 }
 
 // Channel represents the RSS channel.
@@ -23,7 +31,33 @@ type Channel struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
-	Items       []Item `xml:"item"`
+
+	Language      string        `xml:"language,omitempty"`
+	Copyright     string        `xml:"copyright,omitempty"`
+	PubDate       string        `xml:"pubDate,omitempty"`
+	LastBuildDate string        `xml:"lastBuildDate,omitempty"`
+	Generator     string        `xml:"generator,omitempty"`
+	TTL           int           `xml:"ttl,omitempty"`
+	Image         *ChannelImage `xml:"image,omitempty"`
+	AtomLink      *AtomLink     `xml:"atom:link,omitempty"`
+
+	ITunesAuthor   string          `xml:"itunes:author,omitempty"`
+	ITunesImage    *ITunesImage    `xml:"itunes:image,omitempty"`
+	ITunesCategory *ITunesCategory `xml:"itunes:category,omitempty"`
+	ITunesExplicit string          `xml:"itunes:explicit,omitempty"`
+	ITunesType     string          `xml:"itunes:type,omitempty"`
+
+	Items []Item `xml:"item"`
+
+	// Artwork is the program's social image, used for channel artwork.
+	Artwork string `xml:"-"`
+}
+
+// ChannelImage is the image shown for the channel by feed readers.
+type ChannelImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
 }
 
 // Item represents an RSS feed item.
@@ -31,9 +65,100 @@ type Item struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-	GUID        string `xml:"guid"`
-	Thumbnail   string `xml:"thumbnail"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	GUID        GUID   `xml:"guid"`
+
+	ContentEncoded *CDATA `xml:"content:encoded,omitempty"`
+
+	Thumbnail    *MediaThumbnail `xml:"media:thumbnail,omitempty"`
+	MediaContent []MediaContent  `xml:"media:content,omitempty"`
+
+	Enclosure         *Enclosure   `xml:"enclosure,omitempty"`
+	ITunesAuthor      string       `xml:"itunes:author,omitempty"`
+	ITunesImage       *ITunesImage `xml:"itunes:image,omitempty"`
+	ITunesDuration    string       `xml:"itunes:duration,omitempty"`
+	ITunesEpisodeType string       `xml:"itunes:episodeType,omitempty"`
+	ITunesSeason      int          `xml:"itunes:season,omitempty"`
+	ITunesEpisode     int          `xml:"itunes:episode,omitempty"`
+
+	PodcastChapters *PodcastChapters `xml:"podcast:chapters,omitempty"`
+
+	AtomUpdated string `xml:"atom:updated,omitempty"`
+
+	// The fields below are not written to RSS; they carry scraped data between stages.
+	Published time.Time `xml:"-"`
+	// DateOnly marks a Published value whose time of day is not meaningful.
+	DateOnly bool `xml:"-"`
+	// CardDate is the date exactly as the listing card gave it, before time zone handling or
+	// enrichment, so GUIDs made from it stay the same whatever the options.
+	CardDate  time.Time     `xml:"-"`
+	CardID    string        `xml:"-"`
+	Image     Image         `xml:"-"`
+	Duration  time.Duration `xml:"-"`
+	MediaURL  string        `xml:"-"`
+	MediaType string        `xml:"-"`
+	// MediaLength is the size in bytes of the file at MediaURL, 0 when unknown.
+	MediaLength int64     `xml:"-"`
+	Segments    []Segment `xml:"-"`
+	// Source is the episode a story segment belongs to. RSS's <source> names a channel, not a
+	// page, so the episode is only linked from the item body and the Atom and JSON Feed items.
+	Source *Source `xml:"-"`
+	// Enriched is set when the episode's detail page was merged in.
+	Enriched bool `xml:"-"`
+	// Updated and Changes come from an Archive that saw ABC edit the item after publishing it.
+	Updated time.Time    `xml:"-"`
+	Changes []ItemChange `xml:"-"`
+}
+
+// Source links a story segment's item back to its parent episode.
+type Source struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// MediaIndicator is the play badge on a card. Listing pages usually send a bare true for
+// Duration, but some cards carry the running time instead.
+type MediaIndicator struct {
+	Icon     string        `json:"icon"`
+	Duration MediaDuration `json:"duration"`
+}
+
+// MediaDuration decodes a card duration given as a boolean, a number of seconds, or a string
+// such as "14:32" or "PT14M32S". A boolean decodes as zero.
+type MediaDuration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *MediaDuration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*d = MediaDuration(parseDetailDuration(v))
+	return nil
+}
+
+// Image is a picture attached to an item or segment.
+type Image struct {
+	URL    string `json:"url,omitempty"`
+	Alt    string `json:"alt,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	// SrcSet holds srcset candidates such as "https://example.com/a.jpg 700w".
+	SrcSet []string `json:"srcSet,omitempty"`
+}
+
+// Segment is a story within an episode.
+type Segment struct {
+	CardID      string    `json:"cardId,omitempty"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description,omitempty"`
+	Label       string    `json:"label,omitempty"`
+	Image       Image     `json:"image,omitzero"`
+	Published   time.Time `json:"published,omitzero"`
+	// Start is the segment's offset into the episode, when the episode page gives one.
+	Start    time.Duration `json:"start,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
 }
 
 // ABCJSON represents the JSON structure extracted from the page.
@@ -330,109 +455,9 @@ type ABCJSON struct {
 			} `json:"templatePrepared"`
 			Data struct {
 				ComponentsContent []struct {
-					Key            string `json:"key"`
-					Component      string `json:"component"`
-					ComponentProps struct {
-						ID              string `json:"id"`
-						HeadingPrepared string `json:"headingPrepared"`
-						Items           []struct {
-							ArticleLink             string `json:"articleLink"`
-							CardAttributionPrepared struct {
-								PublishedDate       time.Time `json:"publishedDate"`
-								PublishedDateFormat bool      `json:"publishedDateFormat"`
-							} `json:"cardAttributionPrepared"`
-							CardImagePrepared struct {
-								Alt    string `json:"alt"`
-								Ratio  string `json:"ratio"`
-								SrcSet []any  `json:"srcSet"`
-							} `json:"cardImagePrepared"`
-							CardMediaIndicatorPrepared struct {
-								Icon     string `json:"icon"`
-								Duration bool   `json:"duration"`
-							} `json:"cardMediaIndicatorPrepared"`
-							ContentLabelPrepared  any    `json:"contentLabelPrepared"`
-							ContentURI            string `json:"contentUri"`
-							Description           string `json:"description"`
-							CardID                string `json:"cardId"`
-							CardTitle             string `json:"cardTitle"`
-							NoBorders             bool   `json:"noBorders"`
-							PresentersPrepared    any    `json:"presentersPrepared"`
-							ImagePositionPrepared struct {
-								Mobile  string `json:"mobile"`
-								Tablet  string `json:"tablet"`
-								Desktop string `json:"desktop"`
-							} `json:"imagePositionPrepared"`
-							CardContentPositionPrepared struct {
-							} `json:"cardContentPositionPrepared"`
-							HasMobileFeatured bool   `json:"hasMobileFeatured"`
-							DocType           string `json:"docType"`
-							Segments          []struct {
-								ArticleLink             string `json:"articleLink"`
-								CardAttributionPrepared struct {
-									PublishedDate       time.Time `json:"publishedDate"`
-									PublishedDateFormat bool      `json:"publishedDateFormat"`
-								} `json:"cardAttributionPrepared"`
-								CardImagePrepared struct {
-									Alt    string   `json:"alt"`
-									ImgSrc string   `json:"imgSrc"`
-									Ratio  string   `json:"ratio"`
-									SrcSet []string `json:"srcSet"`
-									Width  string   `json:"width"`
-									Height string   `json:"height"`
-								} `json:"cardImagePrepared"`
-								CardMediaIndicatorPrepared struct {
-									Icon     string `json:"icon"`
-									Duration bool   `json:"duration"`
-								} `json:"cardMediaIndicatorPrepared"`
-								ContentLabelPrepared struct {
-									LabelText string `json:"labelText"`
-								} `json:"contentLabelPrepared"`
-								ContentURI            string `json:"contentUri"`
-								Description           string `json:"description"`
-								CardID                string `json:"cardId"`
-								CardTitle             string `json:"cardTitle"`
-								NoBorders             bool   `json:"noBorders"`
-								PresentersPrepared    any    `json:"presentersPrepared"`
-								ImagePositionPrepared struct {
-									Mobile  string `json:"mobile"`
-									Tablet  string `json:"tablet"`
-									Desktop string `json:"desktop"`
-								} `json:"imagePositionPrepared"`
-								CardContentPositionPrepared struct {
-								} `json:"cardContentPositionPrepared"`
-								HasMobileFeatured bool `json:"hasMobileFeatured"`
-							} `json:"segments"`
-							ProgramTemplate string `json:"programTemplate"`
-							Expanded        bool   `json:"expanded"`
-						} `json:"items"`
-						Analytics struct {
-							URI           string `json:"uri"`
-							ModuleURI     string `json:"moduleUri"`
-							ContentSource string `json:"contentSource"`
-							ContentType   string `json:"contentType"`
-							ID            string `json:"id"`
-							Title         struct {
-								Title string `json:"title"`
-							} `json:"title"`
-							Items []struct {
-								URI string `json:"uri"`
-							} `json:"items"`
-						} `json:"analytics"`
-						Pagination struct {
-							CollectionLoaderLimit int `json:"collectionLoaderLimit"`
-							Offset                int `json:"offset"`
-							Size                  int `json:"size"`
-							Total                 int `json:"total"`
-						} `json:"pagination"`
-						ProgramID       any    `json:"programId"`
-						LoadMoreURL     string `json:"loadMoreUrl"`
-						ProgramTemplate string `json:"programTemplate"`
-						HeadingMessage  string `json:"headingMessage"`
-						Variant         string `json:"variant"`
-						Label           string `json:"label"`
-						Description     string `json:"description"`
-						URL             string `json:"url"`
-					} `json:"componentProps,omitempty"`
+					Key                      string         `json:"key"`
+					Component                string         `json:"component"`
+					ComponentProps           ComponentProps `json:"componentProps,omitempty"`
 					CollectionPreparerParams struct {
 						ShowDate bool `json:"showDate"`
 					} `json:"collectionPreparerParams,omitempty"`
@@ -514,31 +539,160 @@ type ABCJSON struct {
 	ScriptLoader          []any  `json:"scriptLoader"`
 }
 
+// ComponentProps holds the properties of a page component such as the EpisodeCollection.
+type ComponentProps struct {
+	ID              string        `json:"id"`
+	HeadingPrepared string        `json:"headingPrepared"`
+	Items           []EpisodeCard `json:"items"`
+	Analytics       struct {
+		URI           string `json:"uri"`
+		ModuleURI     string `json:"moduleUri"`
+		ContentSource string `json:"contentSource"`
+		ContentType   string `json:"contentType"`
+		ID            string `json:"id"`
+		Title         struct {
+			Title string `json:"title"`
+		} `json:"title"`
+		Items []struct {
+			URI string `json:"uri"`
+		} `json:"items"`
+	} `json:"analytics"`
+	Pagination struct {
+		CollectionLoaderLimit int `json:"collectionLoaderLimit"`
+		Offset                int `json:"offset"`
+		Size                  int `json:"size"`
+		Total                 int `json:"total"`
+	} `json:"pagination"`
+	ProgramID       any    `json:"programId"`
+	LoadMoreURL     string `json:"loadMoreUrl"`
+	ProgramTemplate string `json:"programTemplate"`
+	HeadingMessage  string `json:"headingMessage"`
+	Variant         string `json:"variant"`
+	Label           string `json:"label"`
+	Description     string `json:"description"`
+	URL             string `json:"url"`
+}
+
+// EpisodeCard is an episode card in an EpisodeCollection.
+type EpisodeCard struct {
+	ArticleLink             string `json:"articleLink"`
+	CardAttributionPrepared struct {
+		PublishedDate       time.Time `json:"publishedDate"`
+		PublishedDateFormat bool      `json:"publishedDateFormat"`
+	} `json:"cardAttributionPrepared"`
+	CardImagePrepared          CardImage      `json:"cardImagePrepared"`
+	CardMediaIndicatorPrepared MediaIndicator `json:"cardMediaIndicatorPrepared"`
+	ContentLabelPrepared       any            `json:"contentLabelPrepared"`
+	ContentURI                 string         `json:"contentUri"`
+	Description                string         `json:"description"`
+	CardID                     string         `json:"cardId"`
+	CardTitle                  string         `json:"cardTitle"`
+	NoBorders                  bool           `json:"noBorders"`
+	PresentersPrepared         any            `json:"presentersPrepared"`
+	ImagePositionPrepared      struct {
+		Mobile  string `json:"mobile"`
+		Tablet  string `json:"tablet"`
+		Desktop string `json:"desktop"`
+	} `json:"imagePositionPrepared"`
+	CardContentPositionPrepared struct {
+	} `json:"cardContentPositionPrepared"`
+	HasMobileFeatured bool          `json:"hasMobileFeatured"`
+	DocType           string        `json:"docType"`
+	Segments          []SegmentCard `json:"segments"`
+	ProgramTemplate   string        `json:"programTemplate"`
+	Expanded          bool          `json:"expanded"`
+}
+
+// CardImage is the image prepared for an episode or segment card.
+type CardImage struct {
+	Alt    string   `json:"alt"`
+	ImgSrc string   `json:"imgSrc"`
+	Ratio  string   `json:"ratio"`
+	SrcSet []string `json:"srcSet"`
+	Width  string   `json:"width"`
+	Height string   `json:"height"`
+}
+
+// Image converts the card image for use in a feed item.
+func (ci CardImage) Image() Image {
+	width, _ := strconv.Atoi(ci.Width)
+	height, _ := strconv.Atoi(ci.Height)
+	return Image{
+		URL:    ci.ImgSrc,
+		Alt:    ci.Alt,
+		Width:  width,
+		Height: height,
+		SrcSet: ci.SrcSet,
+	}
+}
+
+// SegmentCard is a story card within an episode.
+type SegmentCard struct {
+	ArticleLink             string `json:"articleLink"`
+	CardAttributionPrepared struct {
+		PublishedDate       time.Time `json:"publishedDate"`
+		PublishedDateFormat bool      `json:"publishedDateFormat"`
+	} `json:"cardAttributionPrepared"`
+	CardImagePrepared          CardImage      `json:"cardImagePrepared"`
+	CardMediaIndicatorPrepared MediaIndicator `json:"cardMediaIndicatorPrepared"`
+	ContentLabelPrepared       struct {
+		LabelText string `json:"labelText"`
+	} `json:"contentLabelPrepared"`
+	ContentURI            string `json:"contentUri"`
+	Description           string `json:"description"`
+	CardID                string `json:"cardId"`
+	CardTitle             string `json:"cardTitle"`
+	NoBorders             bool   `json:"noBorders"`
+	PresentersPrepared    any    `json:"presentersPrepared"`
+	ImagePositionPrepared struct {
+		Mobile  string `json:"mobile"`
+		Tablet  string `json:"tablet"`
+		Desktop string `json:"desktop"`
+	} `json:"imagePositionPrepared"`
+	CardContentPositionPrepared struct {
+	} `json:"cardContentPositionPrepared"`
+	HasMobileFeatured bool `json:"hasMobileFeatured"`
+}
+
+// BaseURL is the root of the ABC website.
 const BaseURL = "https://www.abc.net.au"
 
+// Copyright is the copyright notice of the scraped content.
+const Copyright = "Copyright Australian Broadcasting Corporation"
+
+// Generator names this program in the feeds it generates.
+const Generator = "abc-mediawatch-rss (https://github.com/arran4/abc-mediawatch-rss)"
+
+// FetchAndParseToRSS fetches the Media Watch episode listing with a default Client.
 func FetchAndParseToRSS() (RSS, error) {
-	resp, err := http.Get(BaseURL + "/mediawatch/episodes")
-	if err != nil {
-		return RSS{}, fmt.Errorf("fetching news to rss: %v", err)
-	}
-	defer func(Body io.ReadCloser) {
-		if err := Body.Close(); err != nil {
-			log.Printf("Failed to close body: %v", err)
-		}
-	}(resp.Body)
+	return NewClient().FetchFeed(context.Background())
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return RSS{}, fmt.Errorf("status code: %v", resp.Status)
+func closeBody(body io.Closer) {
+	if err := body.Close(); err != nil {
+		log.Printf("Failed to close body: %v", err)
 	}
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// ParseHTML parses a saved episode listing page into an RSS feed with a default Client.
+func ParseHTML(r io.Reader) (RSS, error) {
+	return NewClient().ParseHTML(r)
+}
+
+// ParseNextData extracts and decodes the __NEXT_DATA__ JSON embedded in an ABC page.
+func ParseNextData(r io.Reader) (*ABCJSON, error) {
+	jsonData, err := extractNextData(r)
 	if err != nil {
-		return RSS{}, fmt.Errorf("parsing news to rss: %v", err)
+		return nil, err
 	}
+	return decodeNextData(jsonData)
+}
 
-	rss := RSS{
-		Version: "2.0",
-		Channel: Channel{},
+// extractNextData returns the JSON text of a page's __NEXT_DATA__ script.
+func extractNextData(r io.Reader) ([]byte, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing news to rss: %v", err)
 	}
 
 	// Extract JSON data from a <script> tag.
@@ -548,42 +702,162 @@ func FetchAndParseToRSS() (RSS, error) {
 	})
 
 	if jsonData == "" {
-		return RSS{}, fmt.Errorf("no JSON data found")
+		return nil, fmt.Errorf("no JSON data found")
 	}
+	return []byte(jsonData), nil
+}
 
+func decodeNextData(jsonData []byte) (*ABCJSON, error) {
 	var abcData ABCJSON
-	if err := json.Unmarshal([]byte(jsonData), &abcData); err != nil {
-		return RSS{}, fmt.Errorf("parsing JSON data: %v", err)
+	if err := json.Unmarshal(jsonData, &abcData); err != nil {
+		return nil, fmt.Errorf("parsing JSON data: %v", err)
+	}
+	return &abcData, nil
+}
+
+// ParseHTML parses an episode listing page into an RSS feed.
+func (c *Client) ParseHTML(r io.Reader) (RSS, error) {
+	abcData, err := ParseNextData(r)
+	if err != nil {
+		return RSS{}, err
+	}
+	rss := c.buildRSS(abcData)
+	if err := c.finishRSS(context.Background(), &rss); err != nil {
+		return RSS{}, err
+	}
+	return rss, nil
+}
+
+func (c *Client) buildRSS(abcData *ABCJSON) RSS {
+	rss := RSS{
+		Version: "2.0",
+		Channel: Channel{},
 	}
 
 	// Extract feed header information
-	rss.Channel.Title = abcData.Props.PageProps.HeadTagsSocialPrepared.Site
-	rss.Channel.Link = abcData.Props.PageProps.HeadTagsSocialPrepared.CanonicalURL
-	rss.Channel.Description = abcData.Props.PageProps.HeadTagsSocialPrepared.Description
+	social := abcData.Props.PageProps.HeadTagsSocialPrepared
+	rss.Channel.Title = firstNonEmpty(social.Title, abcData.Props.PageProps.HeadTagsPagePrepared.Title, social.Site)
+	rss.Channel.Link = social.CanonicalURL
+	rss.Channel.Description = firstNonEmpty(social.Description, abcData.Props.PageProps.HeadTagsPagePrepared.Description)
+	rss.Channel.Artwork = social.Image
+	rss.Channel.Language = strings.ToLower(abcData.Props.PageProps.HeadTagsPagePrepared.Lang)
+	rss.Channel.Copyright = Copyright
+	rss.Channel.Generator = Generator
+	if social.Image != "" {
+		rss.Channel.Image = &ChannelImage{URL: social.Image, Title: rss.Channel.Title, Link: rss.Channel.Link}
+	}
 
-	seenGUIDs := map[string]bool{}
+	seenLinks := map[string]bool{}
 
 	// Process components for items
 	for _, component := range abcData.Props.PageProps.Data.ComponentsContent {
 		if component.Component == "EpisodeCollection" {
 
 			for _, listItem := range component.ComponentProps.Items {
-				guid := BaseURL + listItem.ArticleLink
-				if seenGUIDs[guid] {
+				link := NormalizeURL(listItem.ArticleLink)
+				if seenLinks[link] {
 					continue
 				}
-				seenGUIDs[guid] = true
+				seenLinks[link] = true
 
+				published := listItem.CardAttributionPrepared.PublishedDate
 				rss.Channel.Items = append(rss.Channel.Items, Item{
 					Title:       listItem.CardTitle,
-					Link:        guid,
+					Link:        link,
 					Description: listItem.Description,
-					PubDate:     listItem.CardAttributionPrepared.PublishedDate.Format(time.RFC1123),
-					GUID:        guid,
+					DateOnly:    !listItem.CardAttributionPrepared.PublishedDateFormat,
+					Published:   published,
+					CardDate:    published,
+					CardID:      listItem.CardID,
+					Image:       listItem.CardImagePrepared.Image(),
+					Duration:    time.Duration(listItem.CardMediaIndicatorPrepared.Duration),
+					Segments:    segmentsFromCards(listItem.Segments, link),
 				})
 			}
 		}
 	}
 
-	return rss, nil
+	return rss
+}
+
+func segmentsFromCards(cards []SegmentCard, episodeLink string) []Segment {
+	var segments []Segment
+	for _, card := range cards {
+		link := segmentLink(card.ArticleLink, episodeLink, card.CardID)
+		if link == "" {
+			continue
+		}
+		segments = append(segments, Segment{
+			CardID:      card.CardID,
+			Title:       card.CardTitle,
+			Link:        link,
+			Description: card.Description,
+			Label:       card.ContentLabelPrepared.LabelText,
+			Image:       card.CardImagePrepared.Image(),
+			Duration:    time.Duration(card.CardMediaIndicatorPrepared.Duration),
+			Published:   card.CardAttributionPrepared.PublishedDate,
+		})
+	}
+	return segments
+}
+
+// segmentLink is a segment's own page, or for a segment without one, its card within the
+// episode's page. A segment with neither has no link that would tell it apart, and "" is returned.
+func segmentLink(articleLink, episodeLink, cardID string) string {
+	switch {
+	case articleLink != "":
+		return NormalizeURL(articleLink)
+	case cardID != "":
+		return episodeLink + "#" + url.PathEscape(cardID)
+	}
+	return ""
+}
+
+// segmentItems flattens episodes into one item per segment, each pointing back at its episode.
+func segmentItems(episodes []Item) []Item {
+	var items []Item
+	seen := map[string]bool{}
+	for _, episode := range episodes {
+		for _, segment := range episode.Segments {
+			if seen[segment.Link] {
+				continue
+			}
+			seen[segment.Link] = true
+
+			published, cardDate := segment.Published, segment.Published
+			if published.IsZero() {
+				published, cardDate = episode.Published, episode.CardDate
+			}
+			items = append(items, Item{
+				Title:       segment.Title,
+				Link:        segment.Link,
+				Description: segment.Description,
+				Source:      &Source{URL: episode.Link, Title: episode.Title},
+				Published:   published,
+				DateOnly:    segment.Published.IsZero() && episode.DateOnly,
+				CardDate:    cardDate,
+				CardID:      segment.CardID,
+				Image:       segment.Image,
+				Duration:    segment.Duration,
+			})
+		}
+	}
+	return items
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// formatPubDate renders t as an RFC 822 date with a numeric zone offset, or "" for the zero time.
+func formatPubDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC1123Z)
 }
@@ -0,0 +1,354 @@
+package abcrss
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveRecord is one item kept by an Archive, as stored on a line of its file.
+type ArchiveRecord struct {
+	GUID      string    `json:"guid"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Updated is when ABC last edited the item, and History lists every edit seen.
+	Updated time.Time    `json:"updated,omitzero"`
+	History []ItemChange `json:"history,omitempty"`
+	Item    ArchivedItem `json:"item"`
+}
+
+// ArchivedItem is the scraped data of an item, as an Archive stores it. Everything the feed
+// writers derive from it, such as pubDate, is rebuilt when the item is read back.
+type ArchivedItem struct {
+	Title       string        `json:"title"`
+	Link        string        `json:"link"`
+	Description string        `json:"description"`
+	GUID        GUID          `json:"guid"`
+	Source      *Source       `json:"source,omitempty"`
+	Published   time.Time     `json:"published,omitzero"`
+	DateOnly    bool          `json:"dateOnly,omitempty"`
+	CardDate    time.Time     `json:"cardDate,omitzero"`
+	CardID      string        `json:"cardId,omitempty"`
+	Image       Image         `json:"image,omitzero"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	MediaURL    string        `json:"mediaUrl,omitempty"`
+	MediaType   string        `json:"mediaType,omitempty"`
+	MediaLength int64         `json:"mediaLength,omitempty"`
+	Segments    []Segment     `json:"segments,omitempty"`
+	Enriched    bool          `json:"enriched,omitempty"`
+}
+
+// archivedItem keeps the scraped data of item.
+func archivedItem(item Item) ArchivedItem {
+	return ArchivedItem{
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		GUID:        item.GUID,
+		Source:      item.Source,
+		Published:   item.Published,
+		DateOnly:    item.DateOnly,
+		CardDate:    item.CardDate,
+		CardID:      item.CardID,
+		Image:       item.Image,
+		Duration:    item.Duration,
+		MediaURL:    item.MediaURL,
+		MediaType:   item.MediaType,
+		MediaLength: item.MediaLength,
+		Segments:    item.Segments,
+		Enriched:    item.Enriched,
+	}
+}
+
+// Item turns the archived data back into an Item, ready for the feed writers.
+func (a ArchivedItem) Item() Item {
+	return Item{
+		Title:       a.Title,
+		Link:        a.Link,
+		Description: a.Description,
+		GUID:        a.GUID,
+		Source:      a.Source,
+		Published:   a.Published,
+		DateOnly:    a.DateOnly,
+		CardDate:    a.CardDate,
+		CardID:      a.CardID,
+		Image:       a.Image,
+		Duration:    a.Duration,
+		MediaURL:    a.MediaURL,
+		MediaType:   a.MediaType,
+		MediaLength: a.MediaLength,
+		Segments:    a.Segments,
+		Enriched:    a.Enriched,
+	}
+}
+
+// ItemChange is an edit ABC made to an item after it was first archived.
+type ItemChange struct {
+	At    time.Time `json:"at,omitzero"`
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+}
+
+// Retention limits which archived items make it into the feed. Zero values mean no limit.
+type Retention struct {
+	MaxAge   time.Duration
+	MaxItems int
+}
+
+// Archive keeps every item ever scraped in a JSON-lines file, keyed by GUID, so the feed can
+// carry episodes that have dropped off the listing page.
+type Archive struct {
+	path    string
+	records map[string]*ArchiveRecord
+}
+
+// OpenArchive loads the archive at path. A missing file is an empty archive.
+func OpenArchive(path string) (*Archive, error) {
+	a := &Archive{path: path, records: map[string]*ArchiveRecord{}}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %v", err)
+	}
+	defer closeBody(f)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("parsing archive line %d: %v", line, err)
+		}
+		a.records[record.GUID] = &record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading archive: %v", err)
+	}
+	return a, nil
+}
+
+// Records returns every archived record, newest first.
+func (a *Archive) Records() []ArchiveRecord {
+	records := make([]ArchiveRecord, 0, len(a.records))
+	for _, record := range a.records {
+		records = append(records, *record)
+	}
+	slices.SortStableFunc(records, func(x, y ArchiveRecord) int {
+		if c := y.published().Compare(x.published()); c != 0 {
+			return c
+		}
+		return strings.Compare(x.GUID, y.GUID)
+	})
+	return records
+}
+
+// published is when the item went out, or when it was first seen if ABC gave no date.
+func (r ArchiveRecord) published() time.Time {
+	if r.Item.Published.IsZero() {
+		return r.FirstSeen
+	}
+	return r.Item.Published
+}
+
+// Update stores freshly scraped items, which must already have their GUIDs, and returns the
+// ones the archive had never seen. Edits to items it already has are added to their history.
+func (a *Archive) Update(items []Item, now time.Time) []Item {
+	var added []Item
+	for _, item := range items {
+		record, ok := a.records[item.GUID.Value]
+		if !ok {
+			record = &ArchiveRecord{GUID: item.GUID.Value, FirstSeen: now}
+			a.records[record.GUID] = record
+			added = append(added, item)
+		} else {
+			old := record.Item.Item()
+			if old.Enriched && !item.Enriched {
+				item.keepEnrichment(old)
+			}
+			if item.MediaLength == 0 && item.MediaURL == old.MediaURL {
+				item.MediaLength = old.MediaLength
+			}
+			if changes := itemChanges(old, item, now); len(changes) > 0 {
+				record.History = append(record.History, changes...)
+				record.Updated = now
+			}
+		}
+		record.LastSeen = now
+		record.Item = archivedItem(item)
+	}
+	return added
+}
+
+// Items returns the archived items allowed by retention, newest first.
+func (a *Archive) Items(retention Retention, now time.Time) []Item {
+	var items []Item
+	for _, record := range a.Records() {
+		if retention.MaxItems > 0 && len(items) >= retention.MaxItems {
+			break
+		}
+		if retention.MaxAge > 0 && record.published().Before(now.Add(-retention.MaxAge)) {
+			continue
+		}
+		item := record.Item.Item()
+		item.Updated = record.Updated
+		item.Changes = record.History
+		items = append(items, item)
+	}
+	return items
+}
+
+// keepEnrichment carries the detail page data of an earlier run over to an item whose detail
+// page could not be fetched this time, instead of falling back to the card data.
+func (item *Item) keepEnrichment(old Item) {
+	item.Description = old.Description
+	item.Published = old.Published
+	item.DateOnly = old.DateOnly
+	item.Duration = old.Duration
+	item.MediaURL = old.MediaURL
+	item.MediaType = old.MediaType
+	item.MediaLength = old.MediaLength
+	item.Segments = old.Segments
+	item.Enriched = true
+}
+
+// itemChanges compares the fields a reader sees of an archived item with its new scrape. The
+// description is only compared when both come from the same source, since the detail page's
+// long description always differs from the card's.
+func itemChanges(old, new Item, now time.Time) []ItemChange {
+	return compareItems(old, new, now, old.Enriched == new.Enriched)
+}
+
+// compareItems lists the differences in title, link and, if asked, description.
+func compareItems(old, new Item, now time.Time, description bool) []ItemChange {
+	var changes []ItemChange
+	for _, field := range []struct {
+		name, old, new string
+		skip           bool
+	}{
+		{name: "title", old: old.Title, new: new.Title},
+		{name: "description", old: old.Description, new: new.Description, skip: !description},
+		{name: "link", old: old.Link, new: new.Link},
+	} {
+		if !field.skip && field.old != field.new {
+			changes = append(changes, ItemChange{At: now, Field: field.name, Old: field.old, New: field.new})
+		}
+	}
+	return changes
+}
+
+// Save writes the archive back to its file, replacing it atomically.
+func (a *Archive) Save() error {
+	if dir := filepath.Dir(a.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating archive directory: %v", err)
+		}
+	}
+	// Each writer gets its own temporary file, so an interrupted run never clobbers another's.
+	f, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating archive: %v", err)
+	}
+	tmp := f.Name()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, record := range a.Records() {
+		if err := enc.Encode(record); err != nil {
+			closeBody(f)
+			return fmt.Errorf("encoding archive: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		closeBody(f)
+		return fmt.Errorf("writing archive: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing archive: %v", err)
+	}
+	if err := os.Chmod(tmp, 0o644); err != nil {
+		return fmt.Errorf("setting archive permissions: %v", err)
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		return fmt.Errorf("replacing archive: %v", err)
+	}
+	return nil
+}
+
+// WithArchive keeps every scraped item in the JSON-lines archive at path and builds the feed
+// from the archive, limited by retention, instead of only from the current listing page.
+func WithArchive(path string, retention Retention) Option {
+	return func(c *Client) {
+		c.archivePath = path
+		c.retention = retention
+	}
+}
+
+// WithUpdatedTimestamps marks archived items that ABC has edited since they were first seen
+// with the time of the last edit: atom:updated in RSS, updated in Atom and date_modified in
+// JSON Feed.
+func WithUpdatedTimestamps() Option {
+	return func(c *Client) {
+		c.updatedTimestamps = true
+	}
+}
+
+// WithCorrectionNotes adds a "Corrected:" note to the HTML body of archived items that ABC
+// has edited since they were first seen.
+func WithCorrectionNotes() Option {
+	return func(c *Client) {
+		c.correctionNotes = true
+	}
+}
+
+// archiveItems records items in the client's archive, notifies about the new ones and returns
+// the items for the feed. The archive file is locked while it is read, updated and written, so
+// runs sharing it, such as cron next to serve, do not lose each other's records.
+func (c *Client) archiveItems(ctx context.Context, items []Item) ([]Item, error) {
+	unlock, err := c.lockArchive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := OpenArchive(c.archivePath)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	seeding := len(archive.records) == 0
+	now := time.Now()
+	added := archive.Update(items, now)
+	err = archive.Save()
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !seeding {
+		c.notify(ctx, added)
+	}
+	return archive.Items(c.retention, now), nil
+}
+
+// lockArchive takes the archive's lock file, waiting while another run holds it.
+func (c *Client) lockArchive(ctx context.Context) (unlock func(), err error) {
+	if dir := filepath.Dir(c.archivePath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating archive directory: %v", err)
+		}
+	}
+	unlock, err = waitLock(ctx, c.archivePath+".lock", DefaultLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("locking archive: %v", err)
+	}
+	return unlock, nil
+}
@@ -0,0 +1,124 @@
+package abcrss
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestArchiveUpdate(t *testing.T) {
+	link := "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201"
+	card := Item{
+		Title:       "Paper cuts",
+		Link:        link,
+		Description: "The card's short description.",
+		GUID:        GUID{IsPermaLink: true, Value: link},
+		Published:   time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		DateOnly:    true,
+	}
+	enriched := card
+	enriched.Description = "The episode page's long description."
+	enriched.Published = time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC)
+	enriched.DateOnly = false
+	enriched.Duration = 1785 * time.Second
+	enriched.Enriched = true
+
+	retitled := card
+	retitled.Title = "Paper cuts and deadlines"
+	reworded := enriched
+	reworded.Description = "The episode page's corrected description."
+
+	for _, tt := range []struct {
+		name          string
+		first, second Item
+		// fields lists the changes recorded in the history.
+		fields []string
+		// want is the item read back from the archive, without its Updated and Changes.
+		want Item
+	}{
+		{name: "title edit", first: card, second: retitled, fields: []string{"title"}, want: retitled},
+		{name: "failed re-enrichment", first: enriched, second: card, want: enriched},
+		{name: "description edit", first: enriched, second: reworded, fields: []string{"description"}, want: reworded},
+		{name: "unchanged", first: enriched, second: enriched, want: enriched},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := OpenArchive(filepath.Join(t.TempDir(), "archive.jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, second := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC), time.Date(2026, 10, 13, 10, 0, 0, 0, time.UTC)
+			if added := a.Update([]Item{tt.first}, first); len(added) != 1 {
+				t.Errorf("first Update added %d items, want 1", len(added))
+			}
+			if added := a.Update([]Item{tt.second}, second); len(added) != 0 {
+				t.Errorf("second Update added %d items, want 0", len(added))
+			}
+
+			items := a.Items(Retention{}, second)
+			if len(items) != 1 {
+				t.Fatalf("got %d items, want 1", len(items))
+			}
+			got := items[0]
+			var fields []string
+			for _, change := range got.Changes {
+				fields = append(fields, change.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("changes = %+v, want fields %v", got.Changes, tt.fields)
+			}
+			if wantUpdated := len(tt.fields) > 0; got.Updated.Equal(second) != wantUpdated {
+				t.Errorf("Updated = %v, want set: %v", got.Updated, wantUpdated)
+			}
+			got.Updated, got.Changes = time.Time{}, nil
+			if got.Title != tt.want.Title || got.Description != tt.want.Description || !got.Published.Equal(tt.want.Published) ||
+				got.DateOnly != tt.want.DateOnly || got.Duration != tt.want.Duration || got.Enriched != tt.want.Enriched {
+				t.Errorf("item = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchiveKeepsDatesUnlocalized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	published := time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC)
+	item := Item{
+		Title:     "Paper cuts",
+		Link:      "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201",
+		Published: published,
+		CardDate:  published,
+	}
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	for _, tt := range []struct {
+		location *time.Location
+		pubDate  string
+	}{
+		{sydney, "Mon, 12 Oct 2026 20:45:00 +1100"},
+		{time.UTC, "Mon, 12 Oct 2026 09:45:00 +0000"},
+	} {
+		rss := RSS{Channel: Channel{Items: []Item{item}}}
+		if err := NewClient(WithArchive(path, Retention{}), WithLocation(tt.location)).finishRSS(context.Background(), &rss); err != nil {
+			t.Fatal(err)
+		}
+		if got := rss.Channel.Items[0].PubDate; got != tt.pubDate {
+			t.Errorf("%v: pubDate = %q, want %q", tt.location, got, tt.pubDate)
+		}
+
+		a, err := OpenArchive(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := a.Records()
+		if len(records) != 1 {
+			t.Fatalf("got %d records, want 1", len(records))
+		}
+		if got := records[0].Item.Published; !got.Equal(published) || got.Location() != time.UTC {
+			t.Errorf("%v: archived Published = %v, want %v", tt.location, got, published)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package abcrss
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// AtomNamespace is the Atom 1.0 namespace URI.
+const AtomNamespace = "http://www.w3.org/2005/Atom"
+
+// AtomFeed is an Atom 1.0 feed document.
+type AtomFeed struct {
+	XMLName   xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Subtitle  string         `xml:"subtitle,omitempty"`
+	Updated   string         `xml:"updated"`
+	Rights    string         `xml:"rights,omitempty"`
+	Generator *AtomGenerator `xml:"generator,omitempty"`
+	Logo      string         `xml:"logo,omitempty"`
+	Author    AtomPerson     `xml:"author"`
+	Links     []AtomLink     `xml:"link"`
+	Entries   []AtomEntry    `xml:"entry"`
+}
+
+// AtomGenerator names the software that produced the feed.
+type AtomGenerator struct {
+	URI  string `xml:"uri,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// AtomPerson is an Atom author or contributor.
+type AtomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// AtomLink is an Atom link element.
+type AtomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// AtomEntry is an Atom entry.
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published,omitempty"`
+	Links     []AtomLink `xml:"link"`
+	Summary   string     `xml:"summary,omitempty"`
+}
+
+// ToAtom converts a scraped feed into Atom 1.0.
+func ToAtom(rss RSS) AtomFeed {
+	feed := AtomFeed{
+		ID:       rss.Channel.Link,
+		Title:    rss.Channel.Title,
+		Subtitle: rss.Channel.Description,
+		Author:   AtomPerson{Name: "ABC", URI: BaseURL},
+		Links:    []AtomLink{{Rel: "alternate", Href: rss.Channel.Link, Type: "text/html"}},
+		Rights:   rss.Channel.Copyright,
+	}
+	if self := rss.Channel.AtomLink; self != nil {
+		feed.ID = self.Href
+		feed.Links = append(feed.Links, AtomLink{Rel: "self", Href: self.Href, Type: FormatAtom.ContentType()})
+	}
+	if rss.Channel.Generator != "" {
+		feed.Generator = &AtomGenerator{URI: "https://github.com/arran4/abc-mediawatch-rss", Text: "abc-mediawatch-rss"}
+	}
+	if rss.Channel.Image != nil {
+		feed.Logo = rss.Channel.Image.URL
+	}
+
+	var updated time.Time
+	for _, item := range rss.Channel.Items {
+		for _, t := range []time.Time{item.Published, item.Updated} {
+			if t.After(updated) {
+				updated = t
+			}
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	for _, item := range rss.Channel.Items {
+		entry := AtomEntry{
+			ID:      item.GUID.Value,
+			Title:   item.Title,
+			Updated: feed.Updated,
+			Links:   []AtomLink{{Rel: "alternate", Href: item.Link, Type: "text/html"}},
+			Summary: item.Description,
+		}
+		if !item.Published.IsZero() {
+			entry.Published = item.Published.Format(time.RFC3339)
+			entry.Updated = entry.Published
+		}
+		if !item.Updated.IsZero() {
+			entry.Updated = item.Updated.Format(time.RFC3339)
+		}
+		if item.Source != nil {
+			entry.Links = append(entry.Links, AtomLink{Rel: "related", Href: item.Source.URL, Type: "text/html", Title: item.Source.Title})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
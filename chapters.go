@@ -0,0 +1,120 @@
+package abcrss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PodcastNamespace is the Podcasting 2.0 namespace URI.
+const PodcastNamespace = "https://podcastindex.org/namespace/1.0"
+
+// ChaptersType is the MIME type of a JSON Chapters file.
+const ChaptersType = "application/json+chapters"
+
+// PodcastChapters is a podcast:chapters element.
+type PodcastChapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// ChaptersFile is a Podcasting 2.0 JSON Chapters document.
+type ChaptersFile struct {
+	Version  string    `json:"version"`
+	Chapters []Chapter `json:"chapters"`
+}
+
+// Chapter is one entry of a ChaptersFile.
+type Chapter struct {
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime,omitempty"`
+	Title     string  `json:"title"`
+	Img       string  `json:"img,omitempty"`
+	URL       string  `json:"url,omitempty"`
+}
+
+// WithChapters references a JSON Chapters file for every episode whose segment timing is known.
+// The files are expected at baseURL followed by ChaptersFileName; write them with WriteChapters.
+func WithChapters(baseURL string) Option {
+	return func(c *Client) {
+		c.chaptersURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// Chapters builds the chapter list of an episode from its segments. Segments are taken in order;
+// each starts at its own start time when the episode page gave one, otherwise where the previous
+// segment ended. It reports false when a segment's start cannot be worked out.
+func Chapters(item Item) (ChaptersFile, bool) {
+	file := ChaptersFile{Version: "1.2.0"}
+	var end time.Duration
+	endKnown, timed := true, false
+	for _, segment := range item.Segments {
+		var start time.Duration
+		switch {
+		case segment.Start > 0:
+			start = segment.Start
+		case endKnown:
+			start = end
+		default:
+			return ChaptersFile{}, false
+		}
+		chapter := Chapter{
+			StartTime: start.Seconds(),
+			Title:     segment.Title,
+			Img:       segment.Image.URL,
+			URL:       segment.Link,
+		}
+		endKnown = segment.Duration > 0
+		if endKnown {
+			end = start + segment.Duration
+			chapter.EndTime = end.Seconds()
+		}
+		timed = timed || segment.Start > 0 || segment.Duration > 0
+		file.Chapters = append(file.Chapters, chapter)
+	}
+	return file, timed
+}
+
+// ChaptersFileName is the name of the JSON Chapters file for an item, derived from its GUID.
+func ChaptersFileName(item Item) string {
+	sum := sha256.Sum256([]byte(item.GUID.Value))
+	return hex.EncodeToString(sum[:8]) + ".chapters.json"
+}
+
+// addChapters references the chapters file of every item that has one.
+func addChapters(rss *RSS, baseURL string) {
+	for i := range rss.Channel.Items {
+		item := &rss.Channel.Items[i]
+		if _, ok := Chapters(*item); !ok {
+			continue
+		}
+		rss.XMLNSPodcast = PodcastNamespace
+		item.PodcastChapters = &PodcastChapters{URL: baseURL + "/" + ChaptersFileName(*item), Type: ChaptersType}
+	}
+}
+
+// WriteChapters writes the JSON Chapters file of every item in rss that has one into dir.
+func WriteChapters(dir string, rss RSS) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating chapters directory: %v", err)
+	}
+	for _, item := range rss.Channel.Items {
+		chapters, ok := Chapters(item)
+		if !ok {
+			continue
+		}
+		data, err := json.MarshalIndent(chapters, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding chapters: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ChaptersFileName(item)), data, 0o644); err != nil {
+			return fmt.Errorf("writing chapters: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package abcrss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is sent with every request unless overridden with WithUserAgent.
+const DefaultUserAgent = "abc-mediawatch-rss (+https://github.com/arran4/abc-mediawatch-rss)"
+
+// DefaultProgram is the product slug of Media Watch, the program fetched unless WithProgram says otherwise.
+const DefaultProgram = "mediawatch"
+
+var programSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidProgram reports whether slug looks like an ABC product slug such as "fourcorners" or "730".
+func ValidProgram(slug string) bool {
+	return programSlug.MatchString(slug)
+}
+
+// EpisodesPath returns the path of a program's episode listing page.
+func EpisodesPath(program string) string {
+	return "/" + program + "/episodes"
+}
+
+// Fetcher retrieves a page from the ABC site. The path is relative to the site root.
+type Fetcher interface {
+	Fetch(ctx context.Context, path string) (io.ReadCloser, error)
+}
+
+// FetcherFunc adapts an ordinary function to the Fetcher interface.
+type FetcherFunc func(ctx context.Context, path string) (io.ReadCloser, error)
+
+// Fetch calls f(ctx, path).
+func (f FetcherFunc) Fetch(ctx context.Context, path string) (io.ReadCloser, error) {
+	return f(ctx, path)
+}
+
+// Client fetches ABC pages and turns them into feeds.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+	timeout    time.Duration
+	fetcher    Fetcher
+	program    string
+
+	backfill  bool
+	maxPages  int
+	pageDelay time.Duration
+
+	enrichWorkers int
+	segmentFeed   bool
+	podcast       bool
+	chaptersURL   string
+	guidStrategy  GUIDStrategy
+	location      *time.Location
+	selfURL       string
+	ttl           time.Duration
+	archivePath   string
+	retention     Retention
+
+	correctionNotes   bool
+	updatedTimestamps bool
+	notifiers         []Notifier
+	statePath         string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithBaseURL sets the site root that pages are fetched from, for example a local mirror.
+// Links in the generated feed always point at BaseURL.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = u
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithTimeout limits how long a single request, including reading the body, may take.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithFetcher replaces the HTTP fetcher, so pages can come from any source.
+func WithFetcher(f Fetcher) Option {
+	return func(c *Client) {
+		c.fetcher = f
+	}
+}
+
+// WithProgram selects the ABC program to scrape by its product slug, for example "fourcorners".
+func WithProgram(slug string) Option {
+	return func(c *Client) {
+		c.program = slug
+	}
+}
+
+// WithSegmentFeed makes the feed carry one item per story segment instead of one per episode.
+func WithSegmentFeed() Option {
+	return func(c *Client) {
+		c.segmentFeed = true
+	}
+}
+
+// WithSelfURL sets the public URL the feed is served from, advertised with atom:link rel="self".
+func WithSelfURL(u string) Option {
+	return func(c *Client) {
+		c.selfURL = u
+	}
+}
+
+// WithLocation renders dates in loc, for example Australia/Sydney for local broadcast time,
+// instead of the UTC that ABC's data uses.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		c.location = loc
+	}
+}
+
+// WithTTL tells readers how long they may cache the feed before polling again.
+func WithTTL(d time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = d
+	}
+}
+
+// NewClient creates a Client with the given options applied.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    BaseURL,
+		userAgent:  DefaultUserAgent,
+		program:    DefaultProgram,
+		location:   time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.fetcher == nil {
+		c.fetcher = c
+	}
+	return c
+}
+
+// Fetch implements Fetcher by performing an HTTP GET against the base URL.
+func (c *Client) Fetch(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, _, err := c.FetchConditional(ctx, path, Validators{})
+	return body, err
+}
+
+// FetchConditional is Fetch with If-None-Match and If-Modified-Since headers made from v. It
+// returns the validators of the new response, or ErrNotModified if upstream answered 304.
+func (c *Client) FetchConditional(ctx context.Context, path string, v Validators) (io.ReadCloser, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resolve(path), nil)
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("fetching %s: %v", path, err)
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		closeBody(resp.Body)
+		return nil, v, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		closeBody(resp.Body)
+		return nil, Validators{}, fmt.Errorf("status code: %v", resp.Status)
+	}
+	return resp.Body, Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+// do sends req with the configured HTTP client and timeout.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	hc := c.httpClient
+	if c.timeout > 0 {
+		withTimeout := *hc
+		withTimeout.Timeout = c.timeout
+		hc = &withTimeout
+	}
+	return hc.Do(req)
+}
+
+// resolve turns a site path, or an absolute URL on BaseURL, into a URL on the configured base URL.
+func (c *Client) resolve(path string) string {
+	if rest, ok := strings.CutPrefix(path, BaseURL); ok {
+		return c.baseURL + rest
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return c.baseURL + path
+}
+
+// FetchFeed fetches the episode listing and parses it into an RSS feed.
+func (c *Client) FetchFeed(ctx context.Context) (RSS, error) {
+	if !ValidProgram(c.program) {
+		return RSS{}, fmt.Errorf("invalid program slug: %q", c.program)
+	}
+	abcData, err := c.fetchListing(ctx)
+	if err != nil {
+		return RSS{}, err
+	}
+	if c.backfill {
+		if err := c.backfillEpisodes(ctx, abcData); err != nil {
+			return RSS{}, fmt.Errorf("backfilling episodes: %v", err)
+		}
+	}
+	rss := c.buildRSS(abcData)
+	if c.enrichWorkers > 0 {
+		c.enrichItems(ctx, rss.Channel.Items)
+	}
+	if err := c.finishRSS(ctx, &rss); err != nil {
+		return RSS{}, err
+	}
+	return rss, nil
+}
+
+// finishRSS applies the feed-shaping options once all scraping is done.
+func (c *Client) finishRSS(ctx context.Context, rss *RSS) error {
+	rss.Channel.LastBuildDate = formatPubDate(time.Now().In(c.location))
+	rss.Channel.TTL = int(c.ttl.Minutes())
+	if c.selfURL != "" {
+		rss.XMLNSAtom = AtomNamespace
+		rss.Channel.AtomLink = &AtomLink{Rel: "self", Href: c.selfURL, Type: "application/rss+xml"}
+	}
+	if c.segmentFeed {
+		rss.Channel.Title += " stories"
+		rss.Channel.Items = segmentItems(rss.Channel.Items)
+	}
+	assignGUIDs(rss.Channel.Items, c.guidStrategy)
+	if c.archivePath != "" {
+		items, err := c.archiveItems(ctx, rss.Channel.Items)
+		if err != nil {
+			return fmt.Errorf("archiving items: %v", err)
+		}
+		rss.Channel.Items = items
+	}
+	// Dates are localized after archiving, so the archive keeps them as scraped and items read
+	// back from it get this run's time zone.
+	for i := range rss.Channel.Items {
+		item := &rss.Channel.Items[i]
+		item.Published = localDate(item.Published, item.DateOnly, c.location)
+		item.PubDate = formatPubDate(item.Published)
+		for j := range item.Changes {
+			item.Changes[j].At = item.Changes[j].At.In(c.location)
+		}
+		if !c.updatedTimestamps {
+			item.Updated = time.Time{}
+		}
+		if !item.Updated.IsZero() {
+			item.Updated = item.Updated.In(c.location)
+			rss.XMLNSAtom = AtomNamespace
+			item.AtomUpdated = item.Updated.Format(time.RFC3339)
+		}
+	}
+	addMedia(rss)
+	addContent(rss, c.correctionNotes)
+	if c.podcast {
+		addPodcast(rss)
+	}
+	if c.chaptersURL != "" {
+		addChapters(rss, c.chaptersURL)
+	}
+
+	var latest time.Time
+	for _, item := range rss.Channel.Items {
+		if item.Published.After(latest) {
+			latest = item.Published
+		}
+	}
+	if !latest.IsZero() {
+		rss.Channel.PubDate = formatPubDate(latest)
+	}
+	return nil
+}
+
+// localDate moves t into loc. A date-only value becomes midnight of its calendar day in loc.
+func localDate(t time.Time, dateOnly bool, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	t = t.In(loc)
+	if dateOnly {
+		y, m, d := t.Date()
+		t = time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	return t
+}
@@ -1,29 +1,171 @@
 package main
 
 import (
-	"encoding/xml"
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/arran4/abc-mediawatch-rss"
 	"log"
 	"net/http"
 	"net/http/cgi"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 )
 
+// DefaultCacheTTL is how long a rendered feed is served from the cache before it is fetched again.
+const DefaultCacheTTL = 15 * time.Minute
+
 func main() {
-	log.Fatal(cgi.Serve(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		rss, err := abcrss.FetchAndParseToRSS()
+	cache, err := cacheFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// With $ABCRSS_DIAGNOSTIC_FEED set, a failure with nothing cached is answered with a feed
+	// holding a single item that explains the problem, instead of a 500.
+	diagnostic := os.Getenv("ABCRSS_DIAGNOSTIC_FEED") != ""
+	// $ABCRSS_PUBLIC_URL is the script's public address, used for the feed's self link. Without
+	// it the feed has no self link: the request's Host header is chosen by the client, and a
+	// cached feed would hand whatever it said to every reader.
+	publicURL := strings.TrimSuffix(os.Getenv("ABCRSS_PUBLIC_URL"), "?")
+	log.Fatal(cgi.Serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		program := r.URL.Query().Get("program")
+		if program == "" {
+			program = abcrss.DefaultProgram
+		}
+		if !abcrss.ValidProgram(program) {
+			http.Error(w, "Invalid program", http.StatusBadRequest)
+			return
+		}
+		format, err := abcrss.ParseFormat(r.URL.Query().Get("format"))
 		if err != nil {
-			http.Error(w, "Failed to fetch and parse RSS", http.StatusInternalServerError)
+			http.Error(w, "Invalid format", http.StatusBadRequest)
 			return
 		}
-
-		output, err := xml.MarshalIndent(rss, "", "  ")
+		guidStrategy, err := abcrss.ParseGUIDStrategy(r.URL.Query().Get("guid"))
+		if err != nil {
+			http.Error(w, "Invalid guid strategy", http.StatusBadRequest)
+			return
+		}
+		location, err := time.LoadLocation(r.URL.Query().Get("tz"))
 		if err != nil {
-			http.Error(w, "Failed to marshal RSS", http.StatusInternalServerError)
+			http.Error(w, "Invalid time zone", http.StatusBadRequest)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/rss+xml")
-		_, _ = fmt.Fprintf(w, "%s%s", xml.Header, output)
+		var podcast bool
+		if s := r.URL.Query().Get("podcast"); s != "" {
+			if podcast, err = strconv.ParseBool(s); err != nil {
+				http.Error(w, "Invalid podcast", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Only the validated parameters, in canonical form, identify the feed. Anything else a
+		// reader adds, such as a cache-busting timestamp, must not create another cache entry.
+		params := url.Values{"program": {program}}
+		if format != abcrss.FormatRSS {
+			params.Set("format", string(format))
+		}
+		if guidStrategy != abcrss.GUIDPermalink {
+			params.Set("guid", string(guidStrategy))
+		}
+		if location != time.UTC {
+			params.Set("tz", location.String())
+		}
+		if podcast {
+			params.Set("podcast", "1")
+		}
+		opts := []abcrss.Option{
+			abcrss.WithProgram(program),
+			abcrss.WithGUIDStrategy(guidStrategy),
+			abcrss.WithLocation(location),
+		}
+		if publicURL != "" {
+			opts = append(opts, abcrss.WithSelfURL(publicURL+"?"+params.Encode()))
+		}
+		if podcast {
+			opts = append(opts, abcrss.WithPodcast(), abcrss.WithEnrichment(4))
+		}
+
+		build := func() ([]byte, error) {
+			rss, err := abcrss.NewClient(opts...).FetchFeed(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			return abcrss.Marshal(rss, format)
+		}
+		var output []byte
+		modified := time.Now()
+		if cache != nil {
+			output, modified, err = cache.Get(r.Context(), abcrss.CacheKey(params.Encode()), build)
+		} else {
+			output, err = build()
+		}
+		if errors.Is(err, abcrss.ErrStale) {
+			log.Printf("Failed to refresh feed, serving the cached copy: %v", err)
+			w.Header().Set("Warning", abcrss.StaleWarning)
+			err = nil
+		}
+		if err != nil {
+			log.Printf("Failed to build feed: %v", err)
+			if !diagnostic {
+				http.Error(w, "Failed to fetch and parse RSS", http.StatusInternalServerError)
+				return
+			}
+			if output, err = abcrss.Marshal(abcrss.DiagnosticFeed(program, err, time.Now()), format); err != nil {
+				http.Error(w, "Failed to marshal RSS", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Warning", abcrss.DiagnosticWarning)
+			w.Header().Set("Cache-Control", "no-cache")
+			modified = time.Time{}
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		http.ServeContent(w, r, "", modified, bytes.NewReader(output))
 	})))
 }
+
+// cacheFromEnv configures the feed cache from $ABCRSS_CACHE_DIR, which defaults to a private
+// directory under the system temp dir, and $ABCRSS_CACHE_TTL. A TTL of 0 turns caching off.
+func cacheFromEnv() (*abcrss.FileCache, error) {
+	ttl := DefaultCacheTTL
+	if s := os.Getenv("ABCRSS_CACHE_TTL"); s != "" {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid ABCRSS_CACHE_TTL: %v", err)
+		}
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+	dir := os.Getenv("ABCRSS_CACHE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "abcmediawatchrss-cache")
+		if err := privateDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	return &abcrss.FileCache{Dir: dir, TTL: ttl}, nil
+}
+
+// privateDir creates dir readable only by this user, or checks that an existing one is. The
+// temp dir is shared, so anyone could have created dir first and filled it with feeds.
+func privateDir(dir string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("creating cache directory: %v", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("checking cache directory: %v", err)
+	}
+	if !info.IsDir() || info.Mode().Perm()&0o077 != 0 || !ownedByCurrentUser(info) {
+		return fmt.Errorf("cache directory %s is not private to this user; remove it or set ABCRSS_CACHE_DIR", dir)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/arran4/abc-mediawatch-rss"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "abcrss-server.json", "Server configuration file")
+	listen := flag.String("listen", "", "Listen address, overriding the config file")
+	flag.Parse()
+
+	config, err := abcrss.LoadServerConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *listen != "" {
+		config.Listen = *listen
+	}
+	if config.Listen == "" {
+		config.Listen = ":8080"
+	}
+	if len(config.Programs) == 0 {
+		log.Fatal("No programs configured")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	feeds := abcrss.NewFeedServer(config)
+	go feeds.Run(ctx)
+
+	server := &http.Server{Addr: config.Listen, Handler: feeds, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Printf("Failed to shut down: %v", err)
+		}
+	}()
+	log.Printf("Serving %d feeds on %s", len(config.Programs), config.Listen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/arran4/abc-mediawatch-rss"
+	"io"
+	"log"
+	"os"
+)
+
+// runDiff implements the diff subcommand. Like diff(1) it returns 0 when nothing changed,
+// 1 when something did and 2 on errors.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s diff -previous feed.xml [flags]\n\nCompares a fresh scrape with a previous RSS output.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	scrape := addScrapeFlags(fs)
+	previousFile := fs.String("previous", "", "RSS file written by an earlier run; a missing file counts as an empty feed")
+	update := fs.Bool("update", false, "Replace the -previous file with the fresh feed afterwards, ready for the next run")
+	asJSON := fs.Bool("json", false, "Print the changes as JSON")
+	_ = fs.Parse(args)
+	if *previousFile == "" {
+		fs.Usage()
+		return 2
+	}
+
+	previous, err := readPrevious(*previousFile)
+	if err != nil {
+		log.Printf("Failed to read previous feed: %v", err)
+		return 2
+	}
+	rss, err := scrape.load()
+	if err != nil {
+		log.Printf("Failed to fetch and parse new rss: %v", err)
+		return 2
+	}
+
+	diff := abcrss.DiffItems(previous.Channel.Items, rss.Channel.Items)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(diff)
+	} else {
+		err = printDiff(os.Stdout, diff)
+	}
+	if err != nil {
+		log.Printf("Failed to write diff: %v", err)
+		return 2
+	}
+
+	if *update {
+		output, err := abcrss.MarshalRSS(rss)
+		if err != nil {
+			log.Printf("Failed to marshal RSS: %v", err)
+			return 2
+		}
+		if err := os.WriteFile(*previousFile, output, 0o644); err != nil {
+			log.Printf("Failed to update previous feed: %v", err)
+			return 2
+		}
+	}
+	if diff.Empty() {
+		return 0
+	}
+	return 1
+}
+
+func readPrevious(path string) (abcrss.RSS, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return abcrss.RSS{}, nil
+	}
+	if err != nil {
+		return abcrss.RSS{}, err
+	}
+	return abcrss.UnmarshalRSS(data)
+}
+
+func printDiff(w io.Writer, diff abcrss.FeedDiff) error {
+	for _, section := range []struct {
+		mark  string
+		items []abcrss.DiffItem
+	}{
+		{"+", diff.New},
+		{"-", diff.Removed},
+		{"~", diff.Changed},
+	} {
+		for _, item := range section.items {
+			if _, err := fmt.Fprintf(w, "%s %s\n  %s\n", section.mark, item.Title, item.Link); err != nil {
+				return err
+			}
+			for _, change := range item.Changes {
+				if _, err := fmt.Fprintf(w, "  %s: %q -> %q\n", change.Field, change.Old, change.New); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
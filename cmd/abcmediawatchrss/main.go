@@ -1,16 +1,31 @@
 package main
 
 import (
-	"encoding/xml"
+	"context"
+	"errors"
 	"flag"
-	"fmt"
 	"github.com/arran4/abc-mediawatch-rss"
 	"io"
 	"log"
+	"net/http"
+	"net/smtp"
 	"os"
+	"strings"
+	"time"
+	_ "time/tzdata"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+
 	var out io.Writer = os.Stdout
 	setOutputFile := func(s string) error {
 		if oldOut, ok := out.(io.Closer); ok {
@@ -28,19 +43,34 @@ func main() {
 	}
 	flag.Func("o", "Output file", setOutputFile)
 	flag.Func("output", "Output file", setOutputFile)
+	scrape := addScrapeFlags(flag.CommandLine)
+	format := flag.String("format", string(abcrss.FormatRSS), "Output format: rss, atom or json")
+	chaptersDir := flag.String("chapters-dir", "", "Write a JSON Chapters file for each episode with segment timing into this directory")
 	flag.Parse()
-	rss, err := abcrss.FetchAndParseToRSS()
+
+	feedFormat, err := abcrss.ParseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rss, err := scrape.load()
 	if err != nil {
 		log.Fatal("Failed to fetch and parse new rss: ", err)
 	}
 
-	// Output RSS feed
-	output, err := xml.MarshalIndent(rss, "", "  ")
+	if *chaptersDir != "" {
+		if err := abcrss.WriteChapters(*chaptersDir, rss); err != nil {
+			log.Fatalf("Failed to write chapters: %v", err)
+		}
+	}
+
+	// Output feed
+	output, err := abcrss.Marshal(rss, feedFormat)
 	if err != nil {
 		log.Fatalf("Failed to marshal RSS: %v", err)
 	}
 
-	_, err = fmt.Fprintf(out, "%s%s", xml.Header, output)
+	_, err = out.Write(output)
 	if err != nil {
 		log.Fatalf("Failed to format RSS: %v", err)
 	}
@@ -52,3 +82,205 @@ func main() {
 		}
 	}
 }
+
+// scrapeFlags are the flags that control how the feed is scraped, shared by every mode.
+type scrapeFlags struct {
+	input       *string
+	record      *string
+	replay      *string
+	backfill    *bool
+	maxPages    *int
+	pageDelay   *time.Duration
+	enrich      *int
+	segments    *bool
+	program     *string
+	podcast     *bool
+	chaptersURL *string
+	selfURL     *string
+	ttl         *time.Duration
+	guid        *string
+	tz          *string
+	archive     *string
+	retention   *time.Duration
+	maxItems    *int
+	corrections *bool
+	updated     *bool
+	state       *string
+
+	notifyExec         *string
+	notifyWebhook      *string
+	notifyWebhookStyle *string
+	notifySMTP         *string
+	notifySMTPUser     *string
+	notifyFrom         *string
+	notifyTo           *string
+}
+
+func addScrapeFlags(fs *flag.FlagSet) *scrapeFlags {
+	return &scrapeFlags{
+		input:       fs.String("input", "", "Parse a saved episode listing page instead of fetching it (- for stdin)"),
+		record:      fs.String("record", "", "Save every fetched page with its headers into this snapshot directory"),
+		replay:      fs.String("replay", "", "Serve fetched pages from this snapshot directory instead of the network"),
+		backfill:    fs.Bool("backfill", false, "Follow the episode pagination to gather the whole back catalogue"),
+		maxPages:    fs.Int("max-pages", 50, "Maximum number of extra pages fetched when backfilling (0 for no limit)"),
+		pageDelay:   fs.Duration("page-delay", 2*time.Second, "Delay between page requests when backfilling"),
+		enrich:      fs.Int("enrich", 0, "Fetch each episode page with this many workers to add long descriptions, dates, durations and segments"),
+		segments:    fs.Bool("segments", false, "Emit one item per story segment, linking back to its episode"),
+		program:     fs.String("program", abcrss.DefaultProgram, "ABC program product slug, for example fourcorners or 730"),
+		podcast:     fs.Bool("podcast", false, "Add iTunes podcast metadata, and enclosures when used with -enrich"),
+		chaptersURL: fs.String("chapters-url", "", "Public URL of the -chapters-dir directory, referenced from podcast:chapters"),
+		selfURL:     fs.String("self-url", "", "Public URL the feed will be served from, for atom:link rel=\"self\""),
+		ttl:         fs.Duration("ttl", 0, "How long readers may cache the feed, for example 1h"),
+		guid:        fs.String("guid", string(abcrss.GUIDPermalink), "GUID strategy: permalink, cardid or hash"),
+		tz:          fs.String("tz", "UTC", "Time zone for dates, for example Australia/Sydney"),
+		archive:     fs.String("archive", "", "Keep every item ever seen in this JSON-lines file and build the feed from it"),
+		retention:   fs.Duration("retention", 0, "With -archive, leave out items published longer ago than this, for example 8760h"),
+		maxItems:    fs.Int("max-items", 0, "With -archive, the most items the feed carries (0 for no limit)"),
+		corrections: fs.Bool("corrections", false, "With -archive, note ABC's edits to earlier items with \"Corrected:\" in the item body"),
+		updated:     fs.Bool("updated", false, "With -archive, give items ABC has edited the time of the last edit (atom:updated)"),
+		state:       fs.String("state", "", "Keep the listing page's ETag, Last-Modified and data in this file, and skip the download when ABC says it has not changed"),
+
+		notifyExec:         fs.String("notify-exec", "", "With -archive, run this command for each new item with the item as JSON on stdin"),
+		notifyWebhook:      fs.String("notify-webhook", "", "With -archive, POST each new item to this webhook URL"),
+		notifyWebhookStyle: fs.String("notify-webhook-style", string(abcrss.WebhookJSON), "Webhook payload: json, slack, discord or matrix"),
+		notifySMTP:         fs.String("notify-smtp", "", "With -archive, email each new item through this SMTP server (host:port)"),
+		notifySMTPUser:     fs.String("notify-smtp-user", "", "SMTP user name; the password is read from $ABCRSS_SMTP_PASSWORD"),
+		notifyFrom:         fs.String("notify-from", "", "Sender address of notification emails"),
+		notifyTo:           fs.String("notify-to", "", "Comma separated recipients of notification emails"),
+	}
+}
+
+func (f *scrapeFlags) options() ([]abcrss.Option, error) {
+	guidStrategy, err := abcrss.ParseGUIDStrategy(*f.guid)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(*f.tz)
+	if err != nil {
+		return nil, errors.New("invalid time zone: " + err.Error())
+	}
+
+	opts := []abcrss.Option{abcrss.WithProgram(*f.program), abcrss.WithGUIDStrategy(guidStrategy), abcrss.WithLocation(location)}
+	if *f.selfURL != "" {
+		opts = append(opts, abcrss.WithSelfURL(*f.selfURL))
+	}
+	if *f.ttl > 0 {
+		opts = append(opts, abcrss.WithTTL(*f.ttl))
+	}
+	if *f.backfill {
+		opts = append(opts, abcrss.WithBackfill(*f.maxPages, *f.pageDelay))
+	}
+	if *f.segments {
+		opts = append(opts, abcrss.WithSegmentFeed())
+	}
+	if *f.podcast {
+		opts = append(opts, abcrss.WithPodcast())
+	}
+	if *f.chaptersURL != "" {
+		opts = append(opts, abcrss.WithChapters(*f.chaptersURL))
+	}
+	if *f.state != "" {
+		opts = append(opts, abcrss.WithConditionalState(*f.state))
+	}
+	if *f.archive != "" {
+		opts = append(opts, abcrss.WithArchive(*f.archive, abcrss.Retention{MaxAge: *f.retention, MaxItems: *f.maxItems}))
+	}
+	if *f.corrections {
+		opts = append(opts, abcrss.WithCorrectionNotes())
+	}
+	if *f.updated {
+		opts = append(opts, abcrss.WithUpdatedTimestamps())
+	}
+	notifiers, err := f.notifiers()
+	if err != nil {
+		return nil, err
+	}
+	if len(notifiers) > 0 {
+		if *f.archive == "" {
+			return nil, errors.New("notifications need -archive to know which items are new")
+		}
+		opts = append(opts, abcrss.WithNotifiers(notifiers...))
+	}
+	if *f.enrich > 0 {
+		opts = append(opts, abcrss.WithEnrichment(*f.enrich))
+	}
+	switch {
+	case *f.replay != "":
+		opts = append(opts, abcrss.WithHTTPClient(&http.Client{Transport: &abcrss.ReplayTransport{Dir: *f.replay}}))
+	case *f.record != "":
+		opts = append(opts, abcrss.WithHTTPClient(&http.Client{Transport: &abcrss.RecordTransport{Dir: *f.record}}))
+	}
+	return opts, nil
+}
+
+func (f *scrapeFlags) notifiers() ([]abcrss.Notifier, error) {
+	var notifiers []abcrss.Notifier
+	if fields := strings.Fields(*f.notifyExec); len(fields) > 0 {
+		notifiers = append(notifiers, &abcrss.ExecNotifier{Command: fields[0], Args: fields[1:]})
+	}
+	if *f.notifyWebhook != "" {
+		style, err := abcrss.ParseWebhookStyle(*f.notifyWebhookStyle)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, &abcrss.WebhookNotifier{URL: *f.notifyWebhook, Style: style})
+	}
+	if *f.notifySMTP != "" {
+		if *f.notifyFrom == "" || *f.notifyTo == "" {
+			return nil, errors.New("-notify-smtp needs -notify-from and -notify-to")
+		}
+		notifier := &abcrss.SMTPNotifier{Addr: *f.notifySMTP, From: *f.notifyFrom, To: strings.Split(*f.notifyTo, ",")}
+		if *f.notifySMTPUser != "" {
+			host, _, _ := strings.Cut(*f.notifySMTP, ":")
+			notifier.Auth = smtp.PlainAuth("", *f.notifySMTPUser, os.Getenv("ABCRSS_SMTP_PASSWORD"), host)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+// load scrapes the feed as the flags describe.
+func (f *scrapeFlags) load() (abcrss.RSS, error) {
+	if *f.input != "" {
+		// A saved page is parsed as it is; these flags only affect fetching.
+		for _, fetchFlag := range []struct {
+			name string
+			set  bool
+		}{
+			{"enrich", *f.enrich > 0},
+			{"backfill", *f.backfill},
+			{"state", *f.state != ""},
+			{"record", *f.record != ""},
+			{"replay", *f.replay != ""},
+		} {
+			if fetchFlag.set {
+				return abcrss.RSS{}, errors.New("-input cannot be used with -" + fetchFlag.name)
+			}
+		}
+	}
+	opts, err := f.options()
+	if err != nil {
+		return abcrss.RSS{}, err
+	}
+	return loadRSS(abcrss.NewClient(opts...), *f.input)
+}
+
+func loadRSS(client *abcrss.Client, input string) (abcrss.RSS, error) {
+	switch input {
+	case "":
+		return client.FetchFeed(context.Background())
+	case "-":
+		return client.ParseHTML(os.Stdin)
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return abcrss.RSS{}, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Failed to close input: %v", err)
+		}
+	}()
+	return client.ParseHTML(f)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/arran4/abc-mediawatch-rss"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// runServe implements the serve subcommand: it scrapes one program on a schedule and serves
+// the feed from memory at /feeds/{program}.xml.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s serve [flags]\n\nServes the feed over HTTP, re-scraping it on a schedule.\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	scrape := addScrapeFlags(fs)
+	listen := fs.String("listen", ":8080", "Listen address")
+	refresh := fs.Duration("refresh", abcrss.DefaultRefresh, "How often to re-scrape the feed")
+	diagnostic := fs.Bool("diagnostic-feed", false, "Until the first fetch succeeds, serve a feed with one item explaining the failure instead of a 503")
+	_ = fs.Parse(args)
+	if *scrape.input != "" {
+		log.Fatal("-input cannot be used with serve")
+	}
+	if *refresh <= 0 {
+		log.Fatal("-refresh must be positive")
+	}
+
+	opts, err := scrape.options()
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := abcrss.ServerConfig{
+		Listen:         *listen,
+		Programs:       []abcrss.ProgramConfig{{Slug: *scrape.program, Refresh: abcrss.Duration(*refresh)}},
+		DiagnosticFeed: *diagnostic,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	feeds := abcrss.NewFeedServer(config, opts...)
+	go feeds.Run(ctx)
+
+	server := &http.Server{Addr: config.Listen, Handler: feeds, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Printf("Failed to shut down: %v", err)
+		}
+	}()
+	log.Printf("Serving /feeds/%s.xml on %s", *scrape.program, config.Listen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package abcrss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ErrNotModified is returned by FetchConditional when upstream answered 304 Not Modified.
+var ErrNotModified = errors.New("not modified")
+
+// Validators are the cache validators of an upstream response, sent back on the next request
+// so unchanged pages are answered with 304.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// listingState is what WithConditionalState keeps between runs: the listing page's validators
+// and its __NEXT_DATA__ JSON, reused when the page has not changed.
+type listingState struct {
+	URL        string          `json:"url"`
+	Validators Validators      `json:"validators"`
+	NextData   json.RawMessage `json:"nextData"`
+}
+
+// WithConditionalState keeps the episode listing's ETag and Last-Modified in the file at path
+// and sends them with the next fetch. When ABC answers 304 Not Modified, the page data saved
+// from the previous run is used instead of downloading and parsing the page again. It only
+// applies to the Client's own HTTP fetching, not to a WithFetcher replacement.
+func WithConditionalState(path string) Option {
+	return func(c *Client) {
+		c.statePath = path
+	}
+}
+
+// fetchListing fetches and decodes the program's episode listing page.
+func (c *Client) fetchListing(ctx context.Context) (*ABCJSON, error) {
+	path := EpisodesPath(c.program)
+	if c.statePath == "" || c.fetcher != Fetcher(c) {
+		body, err := c.fetcher.Fetch(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("fetching news to rss: %v", err)
+		}
+		defer closeBody(body)
+		return ParseNextData(body)
+	}
+
+	state, err := loadListingState(c.statePath)
+	if err != nil {
+		return nil, err
+	}
+	u := c.resolve(path)
+	if state.URL != u || len(state.NextData) == 0 {
+		state = listingState{URL: u}
+	}
+	body, validators, err := c.FetchConditional(ctx, path, state.Validators)
+	if errors.Is(err, ErrNotModified) {
+		return decodeNextData(state.NextData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching news to rss: %v", err)
+	}
+	defer closeBody(body)
+	jsonData, err := extractNextData(body)
+	if err != nil {
+		return nil, err
+	}
+	abcData, err := decodeNextData(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	state.Validators = validators
+	state.NextData = jsonData
+	if err := saveListingState(c.statePath, state); err != nil {
+		log.Printf("Failed to save listing state: %v", err)
+	}
+	return abcData, nil
+}
+
+// loadListingState reads the state file. A missing file is an empty state.
+func loadListingState(path string) (listingState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return listingState{}, nil
+	}
+	if err != nil {
+		return listingState{}, fmt.Errorf("reading listing state: %v", err)
+	}
+	var state listingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return listingState{}, fmt.Errorf("parsing listing state: %v", err)
+	}
+	return state, nil
+}
+
+func saveListingState(path string, state listingState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding listing state: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing listing state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing listing state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package abcrss
+
+import (
+	"html/template"
+	"log"
+	"strings"
+)
+
+// ContentNamespace is the RSS content module namespace URI.
+const ContentNamespace = "http://purl.org/rss/1.0/modules/content/"
+
+// CDATA is text written as a CDATA section.
+type CDATA struct {
+	Text string `xml:",cdata"`
+}
+
+var contentTemplate = template.Must(template.New("content").Parse(`
+{{- range .Corrections}}<p><strong>Corrected:</strong> the {{.Field}} was changed on {{.At.Format "2 January 2006"}}{{if ne .Field "link"}} from “{{.Old}}”{{end}}.</p>
+{{end -}}
+{{- with .Image}}{{if .URL}}<p><img src="{{.URL}}" alt="{{.Alt}}"{{if .Width}} width="{{.Width}}"{{end}}{{if .Height}} height="{{.Height}}"{{end}}></p>
+{{end}}{{end -}}
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end -}}
+{{with .Source}}<p>From the episode <a href="{{.URL}}">{{.Title}}</a>.</p>
+{{end -}}
+{{if .Segments}}<h3>In this episode</h3>
+<ul>
+{{range .Segments}}<li>{{if .Image.URL}}<img src="{{.Image.URL}}" alt="{{.Image.Alt}}" width="160"> {{end}}<a href="{{.Link}}">{{.Title}}</a>{{with .Label}} <small>{{.}}</small>{{end}}{{with .Description}}<br>{{.}}{{end}}</li>
+{{end}}</ul>
+{{end -}}
+`))
+
+// addContent gives every item an HTML body built from its card: the hero image, the full
+// description and the linked list of segments. The plain description is left as text.
+// With corrections, the edits an Archive recorded are noted at the top.
+func addContent(rss *RSS, corrections bool) {
+	for i := range rss.Channel.Items {
+		item := &rss.Channel.Items[i]
+		var changes []ItemChange
+		if corrections {
+			changes = item.Changes
+		}
+		var buf strings.Builder
+		err := contentTemplate.Execute(&buf, map[string]any{
+			"Image":       item.Image,
+			"Paragraphs":  paragraphs(item.Description),
+			"Source":      item.Source,
+			"Segments":    item.Segments,
+			"Corrections": changes,
+		})
+		if err != nil {
+			log.Printf("Failed to render content for %s: %v", item.Link, err)
+			continue
+		}
+		if buf.Len() == 0 {
+			continue
+		}
+		rss.XMLNSContent = ContentNamespace
+		item.ContentEncoded = &CDATA{Text: buf.String()}
+	}
+}
+
+func paragraphs(text string) []string {
+	var result []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package abcrss
+
+import (
+	"fmt"
+	"time"
+)
+
+// Warning header values sent with feeds that are not fresh.
+const (
+	// StaleWarning marks the last good feed, served because refreshing it failed.
+	StaleWarning = `110 - "Response is Stale"`
+	// DiagnosticWarning marks a DiagnosticFeed.
+	DiagnosticWarning = `199 - "Feed unavailable"`
+)
+
+// DiagnosticFeed is a valid feed with a single item explaining that program's feed could not
+// be fetched. It is served when no good feed exists yet, so readers show the problem instead
+// of an error and do not drop the subscription. The item's GUID changes daily, so readers
+// notice an outage that lasts without being told on every poll.
+func DiagnosticFeed(program string, err error, now time.Time) RSS {
+	link := BaseURL + EpisodesPath(program)
+	return RSS{
+		Version: "2.0",
+		Channel: Channel{
+			Title:       "ABC " + program,
+			Link:        link,
+			Description: "The feed for " + link + " is temporarily unavailable.",
+			Generator:   Generator,
+			Items: []Item{{
+				Title:       "Feed temporarily unavailable",
+				Link:        link,
+				Description: fmt.Sprintf("The episode list could not be fetched from the ABC and will be retried automatically. The error was: %v", err),
+				PubDate:     formatPubDate(now),
+				GUID:        GUID{Value: "urn:abc-mediawatch-rss:unavailable:" + program + ":" + now.UTC().Format(time.DateOnly)},
+				Published:   now,
+			}},
+		},
+	}
+}
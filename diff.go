@@ -0,0 +1,144 @@
+package abcrss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// FeedDiff is what changed between two scrapes of a feed, matching items by GUID.
+type FeedDiff struct {
+	New     []DiffItem `json:"new"`
+	Removed []DiffItem `json:"removed"`
+	Changed []DiffItem `json:"changed"`
+}
+
+// DiffItem identifies an item in a FeedDiff. Changes is only set for changed items.
+type DiffItem struct {
+	GUID    string       `json:"guid"`
+	Title   string       `json:"title"`
+	Link    string       `json:"link"`
+	PubDate string       `json:"pubDate,omitempty"`
+	Changes []ItemChange `json:"changes,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (d FeedDiff) Empty() bool {
+	return len(d.New) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffItems compares the items of a previous feed with the current ones. Unlike the archive, it
+// cannot tell which items were enriched, since RSS does not record it, so descriptions are
+// always compared. Both feeds should come from the same scrape options.
+func DiffItems(previous, current []Item) FeedDiff {
+	diff := FeedDiff{New: []DiffItem{}, Removed: []DiffItem{}, Changed: []DiffItem{}}
+	before := map[string]Item{}
+	for _, item := range previous {
+		before[item.GUID.Value] = item
+	}
+	seen := map[string]bool{}
+	for _, item := range current {
+		seen[item.GUID.Value] = true
+		old, ok := before[item.GUID.Value]
+		if !ok {
+			diff.New = append(diff.New, diffItem(item))
+			continue
+		}
+		if changes := compareItems(old, item, time.Time{}, true); len(changes) > 0 {
+			changed := diffItem(item)
+			changed.Changes = changes
+			diff.Changed = append(diff.Changed, changed)
+		}
+	}
+	for _, item := range previous {
+		if !seen[item.GUID.Value] {
+			diff.Removed = append(diff.Removed, diffItem(item))
+		}
+	}
+	return diff
+}
+
+func diffItem(item Item) DiffItem {
+	return DiffItem{GUID: item.GUID.Value, Title: item.Title, Link: item.Link, PubDate: item.PubDate}
+}
+
+// UnmarshalRSS reads back an RSS document written by MarshalRSS, such as a previous run's output.
+// Only the plain RSS elements are restored.
+func UnmarshalRSS(data []byte) (RSS, error) {
+	var doc plainRSS
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return RSS{}, fmt.Errorf("parsing rss: %v", err)
+	}
+	rss := RSS{Version: doc.Version}
+	for _, el := range doc.Channel.Elements {
+		switch el.name() {
+		case "title":
+			rss.Channel.Title = el.Value
+		case "link":
+			rss.Channel.Link = el.Value
+		case "description":
+			rss.Channel.Description = el.Value
+		case "language":
+			rss.Channel.Language = el.Value
+		case "pubDate":
+			rss.Channel.PubDate = el.Value
+		case "lastBuildDate":
+			rss.Channel.LastBuildDate = el.Value
+		}
+	}
+	for _, plain := range doc.Channel.Items {
+		var item Item
+		for _, el := range plain.Elements {
+			switch el.name() {
+			case "title":
+				item.Title = el.Value
+			case "link":
+				item.Link = el.Value
+			case "description":
+				item.Description = el.Value
+			case "pubDate":
+				item.PubDate = el.Value
+			case "guid":
+				item.GUID = GUID{IsPermaLink: el.attr("isPermaLink") != "false", Value: el.Value}
+			}
+		}
+		rss.Channel.Items = append(rss.Channel.Items, item)
+	}
+	return rss, nil
+}
+
+// plainRSS is what UnmarshalRSS decodes. encoding/xml matches a field tagged "link" against
+// atom:link as well, and "image" against itunes:image, so every element is collected with its
+// name and only those outside any namespace are used.
+type plainRSS struct {
+	Version string `xml:"version,attr"`
+	Channel struct {
+		Elements []plainElement `xml:",any"`
+		Items    []struct {
+			Elements []plainElement `xml:",any"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type plainElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Value   string     `xml:",chardata"`
+}
+
+// name is the element's name, or "" for an element in a namespace.
+func (el plainElement) name() string {
+	if el.XMLName.Space != "" {
+		return ""
+	}
+	return el.XMLName.Local
+}
+
+func (el plainElement) attr(name string) string {
+	for _, attr := range el.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package abcrss
+
+import "testing"
+
+func TestUnmarshalRSSIgnoresNamespacedElements(t *testing.T) {
+	// A field tagged "link" also matches atom:link, and the last match wins, so the namespaced
+	// elements come after the plain ones, as MarshalRSS writes them.
+	rss, err := UnmarshalRSS([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Episodes - Media Watch</title>
+    <link>https://www.abc.net.au/mediawatch/episodes</link>
+    <description>Media Watch episodes</description>
+    <atom:link rel="self" href="https://example.com/feed.xml" type="application/rss+xml"></atom:link>
+    <itunes:image href="https://example.com/art.jpg"></itunes:image>
+    <item>
+      <title>Paper cuts</title>
+      <link>https://www.abc.net.au/mediawatch/episodes/paper-cuts/201</link>
+      <atom:link href="https://example.com/other"></atom:link>
+      <description>The long description of paper cuts.</description>
+      <pubDate>Mon, 12 Oct 2026 09:45:00 +0000</pubDate>
+      <guid isPermaLink="false">urn:abc:card:201</guid>
+    </item>
+  </channel>
+</rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://www.abc.net.au/mediawatch/episodes"; rss.Channel.Link != want {
+		t.Errorf("Channel.Link = %q, want %q", rss.Channel.Link, want)
+	}
+	if rss.Channel.Image != nil || rss.Channel.AtomLink != nil {
+		t.Errorf("namespaced elements restored: image %+v, atom:link %+v", rss.Channel.Image, rss.Channel.AtomLink)
+	}
+	if len(rss.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(rss.Channel.Items))
+	}
+	item := rss.Channel.Items[0]
+	if want := "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201"; item.Link != want {
+		t.Errorf("Link = %q, want %q", item.Link, want)
+	}
+	if want := (GUID{Value: "urn:abc:card:201"}); item.GUID != want {
+		t.Errorf("GUID = %+v, want %+v", item.GUID, want)
+	}
+	if item.Title != "Paper cuts" || item.PubDate != "Mon, 12 Oct 2026 09:45:00 +0000" {
+		t.Errorf("got %+v", item)
+	}
+}
+
+func TestDiffItemsComparesEnrichedDescriptions(t *testing.T) {
+	// A previous feed read back from RSS never knows it was enriched, unlike the fresh scrape.
+	previous := []Item{{Title: "Paper cuts", Link: "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201", Description: "Old words.", GUID: GUID{Value: "201"}}}
+	current := []Item{{Title: "Paper cuts", Link: "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201", Description: "New words.", GUID: GUID{Value: "201"}, Enriched: true}}
+
+	diff := DiffItems(previous, current)
+	if len(diff.Changed) != 1 {
+		t.Fatalf("got %+v, want one changed item", diff)
+	}
+	want := ItemChange{Field: "description", Old: "Old words.", New: "New words."}
+	if changes := diff.Changed[0].Changes; len(changes) != 1 || changes[0] != want {
+		t.Errorf("Changes = %+v, want [%+v]", changes, want)
+	}
+}
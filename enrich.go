@@ -0,0 +1,447 @@
+package abcrss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// EpisodeDetail is the information scraped from an episode's own page.
+type EpisodeDetail struct {
+	Description string
+	Broadcast   time.Time
+	Duration    time.Duration
+	Segments    []Segment
+	MediaURL    string
+	MediaType   string
+}
+
+// WithEnrichment makes FetchFeed fetch every episode's detail page, using up to workers
+// concurrent requests, and merge the details into the item. An item whose page fails keeps
+// its card data.
+func WithEnrichment(workers int) Option {
+	return func(c *Client) {
+		c.enrichWorkers = max(workers, 1)
+	}
+}
+
+// enrichItems fetches the detail page of each item with a bounded pool of workers.
+func (c *Client) enrichItems(ctx context.Context, items []Item) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(c.enrichWorkers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				detail, err := c.FetchEpisodeDetail(ctx, items[i].Link)
+				if err != nil {
+					log.Printf("Failed to enrich %s: %v", items[i].Link, err)
+					continue
+				}
+				items[i].merge(detail)
+				if c.podcast && items[i].MediaURL != "" {
+					length, err := c.mediaLength(ctx, items[i].MediaURL)
+					if err != nil {
+						log.Printf("Failed to size %s: %v", items[i].MediaURL, err)
+						continue
+					}
+					items[i].MediaLength = length
+				}
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// FetchEpisodeDetail fetches and parses an episode's detail page.
+func (c *Client) FetchEpisodeDetail(ctx context.Context, link string) (EpisodeDetail, error) {
+	body, err := c.fetcher.Fetch(ctx, link)
+	if err != nil {
+		return EpisodeDetail{}, err
+	}
+	defer closeBody(body)
+	return ParseEpisodeDetail(body, link)
+}
+
+// mediaLength asks for the size of a media file with a HEAD request, for the podcast enclosure.
+func (c *Client) mediaLength(ctx context.Context, u string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	closeBody(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status code: %v", resp.Status)
+	}
+	if resp.ContentLength <= 0 {
+		return 0, fmt.Errorf("no content length")
+	}
+	return resp.ContentLength, nil
+}
+
+// merge copies whatever the detail page provided over the card data.
+func (item *Item) merge(detail EpisodeDetail) {
+	item.Enriched = true
+	if detail.Description != "" {
+		item.Description = detail.Description
+	}
+	if !detail.Broadcast.IsZero() {
+		item.Published = detail.Broadcast
+		item.DateOnly = false
+	}
+	if detail.Duration > 0 {
+		item.Duration = detail.Duration
+	}
+	if detail.MediaURL != "" {
+		item.MediaURL = detail.MediaURL
+		item.MediaType = detail.MediaType
+	}
+	item.Segments = mergeSegments(item.Segments, detail.Segments)
+}
+
+// mergeSegments lists the segments in the detail page's running order, which chapters rely on.
+// A segment also on the listing card keeps the card's data, with the detail page's timing and
+// any longer description; card segments missing from the detail page go last.
+func mergeSegments(card, detail []Segment) []Segment {
+	if len(detail) == 0 {
+		return card
+	}
+	byLink := map[string]int{}
+	for i, segment := range card {
+		byLink[segment.Link] = i
+	}
+	merged := make([]Segment, 0, max(len(card), len(detail)))
+	used := map[int]bool{}
+	for _, found := range detail {
+		i, ok := byLink[found.Link]
+		if !ok || used[i] {
+			merged = append(merged, found)
+			continue
+		}
+		used[i] = true
+		segment := card[i]
+		if found.Duration > 0 {
+			segment.Duration = found.Duration
+		}
+		if found.Start > 0 {
+			segment.Start = found.Start
+		}
+		if len(found.Description) > len(segment.Description) {
+			segment.Description = found.Description
+		}
+		merged = append(merged, segment)
+	}
+	for i, segment := range card {
+		if !used[i] {
+			merged = append(merged, segment)
+		}
+	}
+	return merged
+}
+
+// ParseEpisodeDetail scrapes the detail page of the episode at link. It prefers schema.org
+// JSON-LD, then the __NEXT_DATA__ JSON, then the page's meta tags, since ABC does not fill all
+// of them consistently.
+func ParseEpisodeDetail(r io.Reader, link string) (EpisodeDetail, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return EpisodeDetail{}, fmt.Errorf("parsing episode page: %v", err)
+	}
+
+	// __NEXT_DATA__ also describes related episodes and the program itself, so only the nodes
+	// describing this episode are searched. JSON-LD describes the page, so all of it is used
+	// when no node names the episode. The plain "description" key is only trusted in JSON-LD.
+	type source struct {
+		nodes           []any
+		descriptionKeys []string
+	}
+	var sources []source
+	collect := func(selector string, whole bool, descriptionKeys ...string) {
+		doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+			var v any
+			if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+				return
+			}
+			nodes := episodeNodes(v, link)
+			if len(nodes) == 0 && whole {
+				nodes = []any{v}
+			}
+			if len(nodes) > 0 {
+				sources = append(sources, source{nodes: nodes, descriptionKeys: descriptionKeys})
+			}
+		})
+	}
+	collect(`script[type="application/ld+json"]`, true, "longDescription", "description")
+	collect("script#__NEXT_DATA__", false, "longDescription", "synopsis")
+
+	var detail EpisodeDetail
+	for _, src := range sources {
+		for _, node := range src.nodes {
+			detail.fill(node, link, src.descriptionKeys)
+		}
+	}
+
+	meta := func(selector string) string {
+		return strings.TrimSpace(doc.Find(selector).First().AttrOr("content", ""))
+	}
+	if detail.Description == "" {
+		detail.Description = meta(`meta[property="og:description"]`)
+	}
+	if detail.Broadcast.IsZero() {
+		detail.Broadcast = parseDetailTime(meta(`meta[property="article:published_time"]`))
+	}
+	if detail.Duration == 0 {
+		detail.Duration = parseDetailDuration(meta(`meta[property="video:duration"]`))
+	}
+	if detail.MediaURL == "" {
+		for _, property := range []string{"og:video:secure_url", "og:video:url", "og:video"} {
+			if u := meta(`meta[property="` + property + `"]`); playableMedia.MatchString(u) {
+				detail.MediaURL = u
+				detail.MediaType = meta(`meta[property="og:video:type"]`)
+				break
+			}
+		}
+	}
+	if detail.Description == "" && detail.Broadcast.IsZero() && detail.Duration == 0 && detail.MediaURL == "" && len(detail.Segments) == 0 {
+		return EpisodeDetail{}, fmt.Errorf("no episode data found")
+	}
+	return detail, nil
+}
+
+// fill sets the details still missing from what node, which describes the episode at link, holds.
+func (detail *EpisodeDetail) fill(node any, link string, descriptionKeys []string) {
+	if detail.Description == "" {
+		detail.Description = findString(node, descriptionKeys...)
+	}
+	if detail.Broadcast.IsZero() {
+		detail.Broadcast = parseDetailTime(findString(node, "broadcastDate", "firstBroadcast", "uploadDate", "datePublished"))
+	}
+	if detail.Duration == 0 {
+		if v, ok := findKey(node, "duration"); ok {
+			detail.Duration = parseDetailDuration(v)
+		}
+	}
+	if len(detail.Segments) == 0 {
+		if v, ok := findKey(node, "segments"); ok {
+			detail.Segments = parseDetailSegments(v, link)
+		}
+	}
+	if detail.MediaURL == "" {
+		if u := findString(node, "contentUrl"); playableMedia.MatchString(u) {
+			detail.MediaURL = u
+			detail.MediaType = findString(node, "encodingFormat")
+		} else if u := findMatching(node, playableMedia); u != "" {
+			detail.MediaURL = u
+		}
+	}
+}
+
+// episodeNodes returns the objects in v that describe the episode at link, shallowest first:
+// those whose URL is the episode's, or whose id is the one ending the episode's link.
+func episodeNodes(v any, link string) []any {
+	link = NormalizeURL(link)
+	id := link[strings.LastIndex(link, "/")+1:]
+	var nodes []any
+	walk(v, func(v any) bool {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		for _, key := range []string{"canonicalURL", "canonicalUrl", "url", "@id", "articleLink", "shareURL"} {
+			if u, ok := m[key].(string); ok && u != "" && NormalizeURL(u) == link {
+				nodes = append(nodes, m)
+				return false
+			}
+		}
+		for _, key := range []string{"id", "documentId", "cardId"} {
+			if s, ok := m[key].(string); ok && id != "" && s == id {
+				nodes = append(nodes, m)
+				return false
+			}
+		}
+		return false
+	})
+	return nodes
+}
+
+// walk visits v and everything inside it breadth first, so shallow values come before deeply
+// nested ones, taking object keys in sorted order. It stops when visit returns true.
+func walk(v any, visit func(any) bool) {
+	queue := []any{v}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if visit(v) {
+			return
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				queue = append(queue, v[k])
+			}
+		case []any:
+			queue = append(queue, v...)
+		}
+	}
+}
+
+// findKey searches v breadth first for the first non-empty, non-boolean value stored under key.
+func findKey(v any, key string) (any, bool) {
+	var found any
+	walk(v, func(v any) bool {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		value, ok := m[key]
+		if _, isBool := value.(bool); !ok || value == nil || value == "" || isBool {
+			return false
+		}
+		found = value
+		return true
+	})
+	return found, found != nil
+}
+
+// playableMedia matches direct links to media files that podcast apps can play.
+var playableMedia = regexp.MustCompile(`(?i)^https?://\S+\.(mp4|m4v|mov|mp3|m4a)(\?\S*)?$`)
+
+// findMatching searches v breadth first for the first string matching re.
+func findMatching(v any, re *regexp.Regexp) string {
+	var found string
+	walk(v, func(v any) bool {
+		if s, ok := v.(string); ok && re.MatchString(s) {
+			found = s
+			return true
+		}
+		return false
+	})
+	return found
+}
+
+// findString returns the first string found under any of keys, tried in order.
+func findString(v any, keys ...string) string {
+	for _, key := range keys {
+		if found, ok := findKey(v, key); ok {
+			if s, ok := found.(string); ok && strings.TrimSpace(s) != "" {
+				return strings.TrimSpace(s)
+			}
+		}
+	}
+	return ""
+}
+
+func parseDetailTime(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:\d+D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?$`)
+
+// parseDetailDuration accepts seconds as a number, ISO 8601 durations such as PT14M32S, and
+// clock style durations such as 14:32 or 1:02:03.
+func parseDetailDuration(v any) time.Duration {
+	switch v := v.(type) {
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return 0
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if m := isoDuration.FindStringSubmatch(v); m != nil {
+			var d time.Duration
+			for i, unit := range []time.Duration{time.Hour, time.Minute} {
+				if n, err := strconv.Atoi(m[i+1]); err == nil {
+					d += time.Duration(n) * unit
+				}
+			}
+			if secs, err := strconv.ParseFloat(m[3], 64); err == nil {
+				d += time.Duration(secs * float64(time.Second))
+			}
+			return d
+		}
+		var d time.Duration
+		for _, part := range strings.Split(v, ":") {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0
+			}
+			d = d*60 + time.Duration(n)*time.Second
+		}
+		return d
+	}
+	return 0
+}
+
+// parseDetailSegments reads the segments of the episode at link, leaving out those with no link
+// of their own, as segmentsFromCards does.
+func parseDetailSegments(v any, episodeLink string) []Segment {
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	var segments []Segment
+	for _, entry := range list {
+		m, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		segment := Segment{
+			CardID:      findString(m, "cardId"),
+			Title:       findString(m, "cardTitle", "title"),
+			Description: findString(m, "description"),
+			Label:       findString(m, "labelText"),
+			Image:       Image{URL: findString(m, "imgSrc"), Alt: findString(m, "alt")},
+			Published:   parseDetailTime(findString(m, "publishedDate")),
+		}
+		segment.Link = segmentLink(findString(m, "articleLink", "url"), NormalizeURL(episodeLink), segment.CardID)
+		if segment.Link == "" {
+			continue
+		}
+		if d, ok := m["duration"]; ok {
+			segment.Duration = parseDetailDuration(d)
+		}
+		for _, key := range []string{"startTime", "start", "offset"} {
+			if start, ok := m[key]; ok {
+				segment.Start = parseDetailDuration(start)
+				break
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
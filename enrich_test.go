@@ -0,0 +1,126 @@
+package abcrss
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func parseEpisodeFixture(t *testing.T, link string) EpisodeDetail {
+	t.Helper()
+	f, err := os.Open("testdata/episode.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeBody(f)
+	detail, err := ParseEpisodeDetail(f, link)
+	if err != nil {
+		t.Fatalf("ParseEpisodeDetail: %v", err)
+	}
+	return detail
+}
+
+func TestParseEpisodeDetailUsesOwnEpisode(t *testing.T) {
+	detail := parseEpisodeFixture(t, "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201")
+
+	if want := "The long description of paper cuts."; detail.Description != want {
+		t.Errorf("Description = %q, want %q", detail.Description, want)
+	}
+	if want := time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC); !detail.Broadcast.Equal(want) {
+		t.Errorf("Broadcast = %v, want %v", detail.Broadcast, want)
+	}
+	if want := 1785 * time.Second; detail.Duration != want {
+		t.Errorf("Duration = %v, want %v", detail.Duration, want)
+	}
+	if want := "https://abcmedia.akamaized.net/mediawatch/video/paper-cuts.mp4"; detail.MediaURL != want {
+		t.Errorf("MediaURL = %q, want %q", detail.MediaURL, want)
+	}
+
+	want := []Segment{
+		{CardID: "1011", Title: "Story 11", Link: "https://www.abc.net.au/mediawatch/story-11/1011", Label: "Video", Duration: 450 * time.Second},
+		{CardID: "1012", Title: "Story 12", Link: "https://www.abc.net.au/mediawatch/story-12/1012", Label: "Video", Start: 450 * time.Second, Duration: 420 * time.Second},
+		// Without a page of its own, a segment links to its card in the episode; without a card
+		// either, it is left out.
+		{CardID: "1013", Title: "Story 13", Link: "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201#1013", Label: "Video", Start: 870 * time.Second, Duration: 300 * time.Second},
+	}
+	if len(detail.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(detail.Segments), len(want), detail.Segments)
+	}
+	for i, segment := range detail.Segments {
+		if segment.CardID != want[i].CardID || segment.Title != want[i].Title || segment.Link != want[i].Link ||
+			segment.Label != want[i].Label || segment.Start != want[i].Start || segment.Duration != want[i].Duration {
+			t.Errorf("segment %d = %+v, want %+v", i, segment, want[i])
+		}
+	}
+}
+
+func TestParseEpisodeDetailIgnoresOtherEpisodes(t *testing.T) {
+	// A page whose data does not name the episode must not lend it a related episode's details.
+	detail := parseEpisodeFixture(t, "https://www.abc.net.au/mediawatch/episodes/something-else/300")
+
+	if want := "The short description of paper cuts."; detail.Description != want {
+		t.Errorf("Description = %q, want %q", detail.Description, want)
+	}
+	if !detail.Broadcast.IsZero() || detail.Duration != 0 || len(detail.Segments) != 0 {
+		t.Errorf("got details of another episode: %+v", detail)
+	}
+	if want := "https://abcmedia.akamaized.net/mediawatch/video/og-fallback.mp4"; detail.MediaURL != want {
+		t.Errorf("MediaURL = %q, want %q", detail.MediaURL, want)
+	}
+}
+
+func TestParseDetailDuration(t *testing.T) {
+	for _, tt := range []struct {
+		in   any
+		want time.Duration
+	}{
+		{float64(90), 90 * time.Second},
+		{"90", 90 * time.Second},
+		{"PT29M45S", 29*time.Minute + 45*time.Second},
+		{"PT1H2M", time.Hour + 2*time.Minute},
+		{"7:30", 7*time.Minute + 30*time.Second},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"soon", 0},
+		{true, 0},
+	} {
+		if got := parseDetailDuration(tt.in); got != tt.want {
+			t.Errorf("parseDetailDuration(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMergeKeepsDetailOnlySegments(t *testing.T) {
+	link := "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201"
+	detail := parseEpisodeFixture(t, link)
+	item := Item{
+		Title: "Paper cuts",
+		Link:  link,
+		Segments: []Segment{
+			{CardID: "1012", Title: "Story 12", Link: "https://www.abc.net.au/mediawatch/story-12/1012", Image: Image{URL: "https://example.com/12.jpg"}},
+			{CardID: "1099", Title: "Only on the card", Link: "https://www.abc.net.au/mediawatch/story-99/1099"},
+		},
+	}
+	item.merge(detail)
+
+	var titles []string
+	for _, segment := range item.Segments {
+		titles = append(titles, segment.Title)
+	}
+	if want := []string{"Story 11", "Story 12", "Story 13", "Only on the card"}; !slices.Equal(titles, want) {
+		t.Fatalf("segments = %v, want %v", titles, want)
+	}
+	if got := item.Segments[1]; got.Image.URL != "https://example.com/12.jpg" || got.Start != 450*time.Second || got.Duration != 420*time.Second {
+		t.Errorf("merged segment = %+v, want the card's image with the detail page's timing", got)
+	}
+
+	chapters, ok := Chapters(item)
+	if !ok || len(chapters.Chapters) < 3 {
+		t.Fatalf("Chapters = %+v, %v, want the three timed segments", chapters, ok)
+	}
+	for i, want := range []float64{0, 450, 870} {
+		if got := chapters.Chapters[i].StartTime; got != want {
+			t.Errorf("chapter %d starts at %v, want %v", i, got, want)
+		}
+	}
+}
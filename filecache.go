@@ -0,0 +1,199 @@
+package abcrss
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockPollInterval is how often FileCache checks whether another process finished refreshing.
+const lockPollInterval = 100 * time.Millisecond
+
+// FileCache keeps rendered feeds on disk, so short-lived processes such as the CGI binary can
+// share them. Refreshes are guarded by a lock file, so concurrent processes do not all fetch
+// upstream at once, and a failed refresh is recorded, so they do not all retry it either.
+type FileCache struct {
+	Dir string
+	TTL time.Duration
+	// LockTimeout is how long a refresh may hold the lock before others treat it as abandoned.
+	LockTimeout time.Duration
+	// FailureBackoff is how long after a failed refresh Get answers with the old entry, or the
+	// failure, instead of trying again. It defaults to the TTL.
+	FailureBackoff time.Duration
+}
+
+// DefaultLockTimeout is used when a FileCache has no LockTimeout.
+const DefaultLockTimeout = 2 * time.Minute
+
+// ErrStale is wrapped by the error FileCache.Get returns along with an old entry when
+// refreshing it failed.
+var ErrStale = errors.New("serving stale cache entry")
+
+// CacheKey turns any string, such as a request URL, into a cache entry name.
+func CacheKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the entry for key and when it was built, calling build to refresh it once it is
+// older than the TTL. While another process holds the refresh lock, a stale entry is returned
+// straight away; without one, Get waits for that process to finish. If build fails and an old
+// entry exists, Get returns the old entry with an error wrapping ErrStale. Until the failure
+// backoff passes, later calls return the same without calling build.
+func (c *FileCache) Get(ctx context.Context, key string, build func() ([]byte, error)) ([]byte, time.Time, error) {
+	path := filepath.Join(c.Dir, key)
+	for {
+		body, modified, err := readCacheEntry(path)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if body != nil && time.Since(modified) < c.TTL {
+			return body, modified, nil
+		}
+		if err := c.recentFailure(path); err != nil {
+			return staleOrFailed(body, modified, err)
+		}
+
+		unlock, locked, err := c.lock(path + ".lock")
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if locked {
+			defer unlock()
+			return c.refresh(path, build)
+		}
+		if body != nil {
+			return body, modified, nil
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+}
+
+// refresh builds the entry while holding its lock. Another process may have refreshed it
+// between our freshness check and taking the lock, in which case its entry is used.
+func (c *FileCache) refresh(path string, build func() ([]byte, error)) ([]byte, time.Time, error) {
+	body, modified, err := readCacheEntry(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if body != nil && time.Since(modified) < c.TTL {
+		return body, modified, nil
+	}
+	if err := c.recentFailure(path); err != nil {
+		return staleOrFailed(body, modified, err)
+	}
+	fresh, err := build()
+	if err != nil {
+		if err := os.WriteFile(path+".failed", []byte(err.Error()), 0o644); err != nil {
+			log.Printf("Failed to record cache refresh failure: %v", err)
+		}
+		return staleOrFailed(body, modified, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, fresh, 0o644); err != nil {
+		return nil, time.Time{}, fmt.Errorf("writing cache entry: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, time.Time{}, fmt.Errorf("replacing cache entry: %v", err)
+	}
+	if err := os.Remove(path + ".failed"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove cache failure marker: %v", err)
+	}
+	return fresh, time.Now(), nil
+}
+
+// recentFailure returns the error recorded by a refresh that failed within the failure backoff.
+func (c *FileCache) recentFailure(path string) error {
+	backoff := c.FailureBackoff
+	if backoff <= 0 {
+		backoff = c.TTL
+	}
+	info, err := os.Stat(path + ".failed")
+	if err != nil || time.Since(info.ModTime()) >= backoff {
+		return nil
+	}
+	msg, err := os.ReadFile(path + ".failed")
+	if err != nil {
+		return nil
+	}
+	return fmt.Errorf("refresh failed %v ago: %s", time.Since(info.ModTime()).Round(time.Second), msg)
+}
+
+// staleOrFailed returns the old entry with an error wrapping ErrStale, or just err without one.
+func staleOrFailed(body []byte, modified time.Time, err error) ([]byte, time.Time, error) {
+	if body != nil {
+		return body, modified, fmt.Errorf("%w: %v", ErrStale, err)
+	}
+	return nil, time.Time{}, err
+}
+
+// lock takes the entry's lock file without waiting.
+func (c *FileCache) lock(path string) (unlock func(), locked bool, err error) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, false, fmt.Errorf("creating cache directory: %v", err)
+	}
+	timeout := c.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return lockFile(path, timeout)
+}
+
+// lockFile takes the lock file at path with O_EXCL, which works on every platform. A lock older
+// than timeout is left over from a crashed process and is removed.
+func lockFile(path string, timeout time.Duration) (unlock func(), locked bool, err error) {
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > timeout {
+		log.Printf("Removing abandoned lock %s", path)
+		_ = os.Remove(path)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("creating lock: %v", err)
+	}
+	closeBody(f)
+	return func() {
+		if err := os.Remove(path); err != nil {
+			log.Printf("Failed to remove lock: %v", err)
+		}
+	}, true, nil
+}
+
+// waitLock takes the lock file at path, waiting while another process holds it.
+func waitLock(ctx context.Context, path string, timeout time.Duration) (unlock func(), err error) {
+	for {
+		unlock, locked, err := lockFile(path, timeout)
+		if err != nil || locked {
+			return unlock, err
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readCacheEntry returns a nil body when the entry does not exist yet.
+func readCacheEntry(path string) ([]byte, time.Time, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("checking cache entry: %v", err)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("reading cache entry: %v", err)
+	}
+	return body, info.ModTime(), nil
+}
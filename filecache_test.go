@@ -0,0 +1,143 @@
+package abcrss
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingBuild returns a build function answering with body, or failing when body is nil,
+// and counts its calls.
+func countingBuild(calls *int, body []byte) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		*calls++
+		if body == nil {
+			return nil, errors.New("upstream down")
+		}
+		return body, nil
+	}
+}
+
+// age backdates a cache file, as if it were written d ago.
+func age(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	then := time.Now().Add(-d)
+	if err := os.Chtimes(path, then, then); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCacheServesStaleEntryAndBacksOff(t *testing.T) {
+	c := &FileCache{Dir: t.TempDir(), TTL: time.Minute}
+	ctx := context.Background()
+	var calls int
+
+	if body, _, err := c.Get(ctx, "feed", countingBuild(&calls, []byte("first"))); err != nil || string(body) != "first" {
+		t.Fatalf("Get = %q, %v", body, err)
+	}
+	if body, _, err := c.Get(ctx, "feed", countingBuild(&calls, []byte("second"))); err != nil || string(body) != "first" || calls != 1 {
+		t.Fatalf("fresh Get = %q, %v after %d builds, want the cached entry", body, err, calls)
+	}
+
+	age(t, filepath.Join(c.Dir, "feed"), 2*time.Minute)
+	body, _, err := c.Get(ctx, "feed", countingBuild(&calls, nil))
+	if !errors.Is(err, ErrStale) || string(body) != "first" || calls != 2 {
+		t.Fatalf("failed refresh = %q, %v after %d builds, want the stale entry with ErrStale", body, err, calls)
+	}
+	body, _, err = c.Get(ctx, "feed", countingBuild(&calls, []byte("second")))
+	if !errors.Is(err, ErrStale) || !strings.Contains(err.Error(), "upstream down") || string(body) != "first" || calls != 2 {
+		t.Fatalf("Get during backoff = %q, %v after %d builds, want the stale entry without building", body, err, calls)
+	}
+
+	age(t, filepath.Join(c.Dir, "feed.failed"), 2*time.Minute)
+	if body, _, err := c.Get(ctx, "feed", countingBuild(&calls, []byte("second"))); err != nil || string(body) != "second" || calls != 3 {
+		t.Fatalf("Get after backoff = %q, %v after %d builds, want a fresh entry", body, err, calls)
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "feed.failed")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("failure marker left after a successful refresh: %v", err)
+	}
+}
+
+func TestFileCacheBacksOffWithoutEntry(t *testing.T) {
+	c := &FileCache{Dir: t.TempDir(), TTL: time.Minute, FailureBackoff: time.Hour}
+	var calls int
+	for range 3 {
+		body, _, err := c.Get(context.Background(), "feed", countingBuild(&calls, nil))
+		if err == nil || errors.Is(err, ErrStale) || body != nil {
+			t.Fatalf("Get = %q, %v, want the build error", body, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("build called %d times, want 1", calls)
+	}
+}
+
+func TestFileCacheLockHandoff(t *testing.T) {
+	c := &FileCache{Dir: t.TempDir(), TTL: time.Minute}
+	ctx := context.Background()
+	release := make(chan struct{})
+	building := make(chan struct{})
+	var mu sync.Mutex
+	var calls int
+	slow := func() ([]byte, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		close(building)
+		<-release
+		return []byte("feed"), nil
+	}
+	fast := func() ([]byte, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return []byte("other"), nil
+	}
+
+	done := make(chan []byte)
+	go func() {
+		body, _, err := c.Get(ctx, "feed", slow)
+		if err != nil {
+			t.Errorf("first Get: %v", err)
+		}
+		done <- body
+	}()
+	<-building
+	// With nothing cached, the second caller waits for the first one's entry instead of
+	// building its own.
+	go func() {
+		time.Sleep(2 * lockPollInterval)
+		close(release)
+	}()
+	body, _, err := c.Get(ctx, "feed", fast)
+	if err != nil || string(body) != "feed" {
+		t.Errorf("waiting Get = %q, %v, want the first caller's entry", body, err)
+	}
+	if first := <-done; string(first) != "feed" {
+		t.Errorf("first Get = %q", first)
+	}
+	if calls != 1 {
+		t.Errorf("build called %d times, want 1", calls)
+	}
+}
+
+func TestFileCacheRemovesAbandonedLock(t *testing.T) {
+	c := &FileCache{Dir: t.TempDir(), TTL: time.Minute, LockTimeout: time.Minute}
+	lock := filepath.Join(c.Dir, "feed.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	age(t, lock, 2*time.Minute)
+	var calls int
+	if body, _, err := c.Get(context.Background(), "feed", countingBuild(&calls, []byte("feed"))); err != nil || string(body) != "feed" {
+		t.Fatalf("Get = %q, %v", body, err)
+	}
+	if _, err := os.Stat(lock); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("lock left behind: %v", err)
+	}
+}
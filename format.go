@@ -0,0 +1,64 @@
+package abcrss
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Format is a feed serialization.
+type Format string
+
+// Supported feed formats.
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a format name such as "rss", "atom" or "json".
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatRSS, FormatAtom, FormatJSON:
+		return f, nil
+	case "":
+		return FormatRSS, nil
+	}
+	return "", fmt.Errorf("unknown feed format: %q", s)
+}
+
+// ContentType is the MIME type to serve the format with.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatAtom:
+		return "application/atom+xml"
+	case FormatJSON:
+		return "application/feed+json"
+	}
+	return "application/rss+xml"
+}
+
+// Marshal renders rss in the given format.
+func Marshal(rss RSS, f Format) ([]byte, error) {
+	switch f {
+	case FormatAtom:
+		return marshalXML(ToAtom(rss))
+	case FormatJSON:
+		return marshalJSONFeed(rss)
+	case FormatRSS, "":
+		return marshalXML(rss)
+	}
+	return nil, fmt.Errorf("unknown feed format: %q", f)
+}
+
+// MarshalRSS renders rss as an RSS 2.0 document.
+func MarshalRSS(rss RSS) ([]byte, error) {
+	return marshalXML(rss)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	output, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), output...), nil
+}
@@ -0,0 +1,86 @@
+package abcrss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GUID is an RSS item guid.
+type GUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr" json:"isPermaLink"`
+	Value       string `xml:",chardata" json:"value"`
+}
+
+// GUIDStrategy decides how item GUIDs are made.
+type GUIDStrategy string
+
+// Supported GUID strategies.
+const (
+	// GUIDPermalink uses the item's normalized URL.
+	GUIDPermalink GUIDStrategy = "permalink"
+	// GUIDCardID uses ABC's card id, which survives URL slug changes.
+	GUIDCardID GUIDStrategy = "cardid"
+	// GUIDHash uses a hash of the item's title and the UTC date on its listing card. Corrected
+	// titles get new GUIDs.
+	GUIDHash GUIDStrategy = "hash"
+)
+
+// ParseGUIDStrategy validates a strategy name such as "permalink", "cardid" or "hash".
+func ParseGUIDStrategy(s string) (GUIDStrategy, error) {
+	switch g := GUIDStrategy(s); g {
+	case GUIDPermalink, GUIDCardID, GUIDHash:
+		return g, nil
+	case "":
+		return GUIDPermalink, nil
+	}
+	return "", fmt.Errorf("unknown guid strategy: %q", s)
+}
+
+// WithGUIDStrategy selects how item GUIDs are made. The default is GUIDPermalink.
+func WithGUIDStrategy(g GUIDStrategy) Option {
+	return func(c *Client) {
+		c.guidStrategy = g
+	}
+}
+
+var baseURL, _ = url.Parse(BaseURL)
+
+// NormalizeURL resolves a link, relative or absolute, against BaseURL and strips its query and
+// fragment, so the same page always gets the same URL.
+func NormalizeURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return link
+	}
+	u = baseURL.ResolveReference(u)
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// guidFor makes an item's GUID with the given strategy, falling back to the permalink when the
+// strategy has nothing to work with.
+func guidFor(item Item, strategy GUIDStrategy) GUID {
+	switch strategy {
+	case GUIDCardID:
+		if item.CardID != "" {
+			return GUID{Value: "urn:abc:card:" + item.CardID}
+		}
+	case GUIDHash:
+		sum := sha256.Sum256([]byte(item.Title + "\n" + item.CardDate.UTC().Format(time.DateOnly)))
+		return GUID{Value: "urn:sha256:" + hex.EncodeToString(sum[:16])}
+	}
+	return GUID{IsPermaLink: true, Value: item.Link}
+}
+
+func assignGUIDs(items []Item, strategy GUIDStrategy) {
+	for i := range items {
+		items[i].GUID = guidFor(items[i], strategy)
+	}
+}
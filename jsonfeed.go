@@ -0,0 +1,143 @@
+package abcrss
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// JSONFeedVersion is the version URL of the JSON Feed specification produced.
+const JSONFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// JSONFeed is a JSON Feed 1.1 document.
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url,omitempty"`
+	FeedURL     string           `json:"feed_url,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Icon        string           `json:"icon,omitempty"`
+	Language    string           `json:"language,omitempty"`
+	Authors     []JSONFeedAuthor `json:"authors,omitempty"`
+	Items       []JSONFeedItem   `json:"items"`
+}
+
+// JSONFeedAuthor is a JSON Feed author object.
+type JSONFeedAuthor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// JSONFeedItem is a JSON Feed item.
+type JSONFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentText   string           `json:"content_text"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []JSONFeedAuthor `json:"authors,omitempty"`
+	ABC           *JSONFeedABC     `json:"_abc,omitempty"`
+}
+
+// JSONFeedABC is the "_abc" extension object carrying ABC specific data on an item.
+type JSONFeedABC struct {
+	DurationSeconds float64           `json:"duration_seconds,omitempty"`
+	Episode         *JSONFeedEpisode  `json:"episode,omitempty"`
+	Segments        []JSONFeedSegment `json:"segments,omitempty"`
+}
+
+// JSONFeedEpisode points a segment item back at its episode.
+type JSONFeedEpisode struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+// JSONFeedSegment is a story segment within an episode item.
+type JSONFeedSegment struct {
+	Title           string  `json:"title"`
+	URL             string  `json:"url"`
+	Summary         string  `json:"summary,omitempty"`
+	Label           string  `json:"label,omitempty"`
+	Image           string  `json:"image,omitempty"`
+	DatePublished   string  `json:"date_published,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+var jsonFeedAuthors = []JSONFeedAuthor{{Name: "ABC", URL: BaseURL}}
+
+// ToJSONFeed converts a scraped feed into JSON Feed 1.1.
+func ToJSONFeed(rss RSS) JSONFeed {
+	feed := JSONFeed{
+		Version:     JSONFeedVersion,
+		Title:       rss.Channel.Title,
+		HomePageURL: rss.Channel.Link,
+		Description: rss.Channel.Description,
+		Authors:     jsonFeedAuthors,
+		Language:    rss.Channel.Language,
+		Items:       []JSONFeedItem{},
+	}
+	if rss.Channel.Image != nil {
+		feed.Icon = rss.Channel.Image.URL
+	}
+	if self := rss.Channel.AtomLink; self != nil {
+		feed.FeedURL = self.Href
+	}
+	for _, item := range rss.Channel.Items {
+		feed.Items = append(feed.Items, ToJSONFeedItem(item))
+	}
+	return feed
+}
+
+// ToJSONFeedItem converts one scraped item into a JSON Feed item.
+func ToJSONFeedItem(item Item) JSONFeedItem {
+	jsonItem := JSONFeedItem{
+		ID:            item.GUID.Value,
+		URL:           item.Link,
+		Title:         item.Title,
+		ContentText:   item.Description,
+		Image:         item.Image.URL,
+		DatePublished: jsonFeedDate(item.Published),
+		DateModified:  jsonFeedDate(item.Updated),
+		Authors:       jsonFeedAuthors,
+	}
+
+	abc := JSONFeedABC{DurationSeconds: item.Duration.Seconds()}
+	if item.Source != nil {
+		abc.Episode = &JSONFeedEpisode{Title: item.Source.Title, URL: item.Source.URL}
+	}
+	for _, segment := range item.Segments {
+		abc.Segments = append(abc.Segments, JSONFeedSegment{
+			Title:           segment.Title,
+			URL:             segment.Link,
+			Summary:         segment.Description,
+			Label:           segment.Label,
+			Image:           segment.Image.URL,
+			DatePublished:   jsonFeedDate(segment.Published),
+			DurationSeconds: segment.Duration.Seconds(),
+		})
+	}
+	if abc.DurationSeconds > 0 || abc.Episode != nil || len(abc.Segments) > 0 {
+		jsonItem.ABC = &abc
+	}
+	return jsonItem
+}
+
+func jsonFeedDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func marshalJSONFeed(rss RSS) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(ToJSONFeed(rss)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package abcrss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MediaNamespace is the Media RSS namespace URI.
+const MediaNamespace = "http://search.yahoo.com/mrss/"
+
+// MediaThumbnail is a Media RSS thumbnail.
+type MediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+// MediaContent is a Media RSS content element.
+type MediaContent struct {
+	URL         string            `xml:"url,attr"`
+	Type        string            `xml:"type,attr,omitempty"`
+	Medium      string            `xml:"medium,attr,omitempty"`
+	Width       int               `xml:"width,attr,omitempty"`
+	Height      int               `xml:"height,attr,omitempty"`
+	Description *MediaDescription `xml:"media:description,omitempty"`
+}
+
+// MediaDescription is a Media RSS description, used for image alt text.
+type MediaDescription struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// addMedia fills in the Media RSS elements of every item from its image.
+func addMedia(rss *RSS) {
+	for i := range rss.Channel.Items {
+		item := &rss.Channel.Items[i]
+		if item.Image.URL == "" {
+			continue
+		}
+		rss.XMLNSMedia = MediaNamespace
+		item.Thumbnail = &MediaThumbnail{URL: item.Image.URL, Width: item.Image.Width, Height: item.Image.Height}
+
+		var description *MediaDescription
+		if item.Image.Alt != "" {
+			description = &MediaDescription{Type: "plain", Text: item.Image.Alt}
+		}
+		item.MediaContent = []MediaContent{{
+			URL:         item.Image.URL,
+			Medium:      "image",
+			Width:       item.Image.Width,
+			Height:      item.Image.Height,
+			Description: description,
+		}}
+		for _, candidate := range item.Image.SrcSet {
+			u, width := parseSrcSetCandidate(candidate)
+			if u == "" || u == item.Image.URL {
+				continue
+			}
+			item.MediaContent = append(item.MediaContent, MediaContent{
+				URL:         u,
+				Medium:      "image",
+				Width:       width,
+				Description: description,
+			})
+		}
+	}
+}
+
+// parseSrcSetCandidate splits a srcset candidate such as "https://example.com/a.jpg 700w"
+// into its URL and width. The width is 0 when the candidate has no width descriptor.
+func parseSrcSetCandidate(candidate string) (string, int) {
+	fields := strings.Fields(candidate)
+	if len(fields) == 0 {
+		return "", 0
+	}
+	if len(fields) > 1 {
+		if w, ok := strings.CutSuffix(fields[1], "w"); ok {
+			width, _ := strconv.Atoi(w)
+			return fields[0], width
+		}
+	}
+	return fields[0], 0
+}
@@ -0,0 +1,246 @@
+package abcrss
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Notifier is told about every item the archive had not seen before.
+type Notifier interface {
+	Notify(ctx context.Context, item Item) error
+}
+
+// NotifierFunc adapts an ordinary function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, item Item) error
+
+// Notify calls f(ctx, item).
+func (f NotifierFunc) Notify(ctx context.Context, item Item) error {
+	return f(ctx, item)
+}
+
+// WithNotifiers fires notifiers for every new item found by the archive set with WithArchive.
+// The run that creates the archive only fills it, so subscribers are not told about the whole
+// back catalogue at once.
+func WithNotifiers(notifiers ...Notifier) Option {
+	return func(c *Client) {
+		c.notifiers = append(c.notifiers, notifiers...)
+	}
+}
+
+// NotifyTimeout bounds each notification, so a hung webhook or mail server cannot hold up the
+// feed.
+const NotifyTimeout = 30 * time.Second
+
+// notify sends every item to every notifier, logging failures so one broken sink does not
+// stop the others.
+func (c *Client) notify(ctx context.Context, items []Item) {
+	for _, item := range items {
+		for _, notifier := range c.notifiers {
+			ctx, cancel := context.WithTimeout(ctx, NotifyTimeout)
+			if err := notifier.Notify(ctx, item); err != nil {
+				log.Printf("Failed to notify about %s: %v", item.Link, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// notificationText is the plain text announcement of a new item.
+func notificationText(item Item) string {
+	return "New: " + item.Title + "\n" + item.Link
+}
+
+// ExecNotifier runs a local command for every new item, with the item as a JSON Feed item on stdin.
+type ExecNotifier struct {
+	Command string
+	Args    []string
+}
+
+// Notify implements Notifier.
+func (n *ExecNotifier) Notify(ctx context.Context, item Item) error {
+	payload, err := json.Marshal(ToJSONFeedItem(item))
+	if err != nil {
+		return fmt.Errorf("encoding item: %v", err)
+	}
+	cmd := exec.CommandContext(ctx, n.Command, n.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running %s: %v: %s", n.Command, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// WebhookStyle selects the payload a WebhookNotifier posts.
+type WebhookStyle string
+
+// Supported webhook payloads.
+const (
+	// WebhookJSON posts the item as a JSON Feed item.
+	WebhookJSON    WebhookStyle = "json"
+	WebhookSlack   WebhookStyle = "slack"
+	WebhookDiscord WebhookStyle = "discord"
+	// WebhookMatrix posts the text and HTML message accepted by Matrix webhook bridges such as hookshot.
+	WebhookMatrix WebhookStyle = "matrix"
+)
+
+// ParseWebhookStyle validates a webhook style name. An empty name is WebhookJSON.
+func ParseWebhookStyle(s string) (WebhookStyle, error) {
+	switch style := WebhookStyle(s); style {
+	case WebhookJSON, WebhookSlack, WebhookDiscord, WebhookMatrix:
+		return style, nil
+	case "":
+		return WebhookJSON, nil
+	}
+	return "", fmt.Errorf("unknown webhook style: %q", s)
+}
+
+// WebhookNotifier POSTs every new item to a webhook URL.
+type WebhookNotifier struct {
+	URL   string
+	Style WebhookStyle
+	// Client is used for the request, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, item Item) error {
+	var payload any
+	switch n.Style {
+	case WebhookSlack:
+		payload = map[string]string{"text": "New: <" + slackEscaper.Replace(item.Link) + "|" + slackEscaper.Replace(item.Title) + ">"}
+	case WebhookDiscord:
+		payload = map[string]string{"content": notificationText(item)}
+	case WebhookMatrix:
+		payload = map[string]string{
+			"text": notificationText(item),
+			"html": `New: <a href="` + html.EscapeString(item.Link) + `">` + html.EscapeString(item.Title) + `</a>`,
+		}
+	default:
+		payload = ToJSONFeedItem(item)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	hc := n.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting webhook: %v", err)
+	}
+	closeBody(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status code: %v", resp.Status)
+	}
+	return nil
+}
+
+// slackEscaper escapes the characters Slack's message formatting reserves.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SMTPNotifier emails every new item.
+type SMTPNotifier struct {
+	// Addr is the host:port of the mail server.
+	Addr string
+	From string
+	To   []string
+	// Auth is optional; net/smtp only sends credentials over TLS or to localhost.
+	Auth smtp.Auth
+}
+
+// Notify implements Notifier. The connection is abandoned when ctx is done.
+func (n *SMTPNotifier) Notify(ctx context.Context, item Item) error {
+	var msg bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", n.From},
+		{"To", strings.Join(n.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", "New: "+item.Title)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", header[0], header[1])
+	}
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(notificationText(item)+"\n\n"+item.Description+"\n", "\n", "\r\n"))
+	if err := n.send(ctx, msg.Bytes()); err != nil {
+		return fmt.Errorf("sending mail: %v", err)
+	}
+	return nil
+}
+
+// send does what smtp.SendMail does, over a connection that is cut off when ctx is done.
+func (n *SMTPNotifier) send(ctx context.Context, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(n.Addr)
+	// NewClient closes conn itself when the greeting fails.
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	// Like smtp.SendMail, ignore the error from closing a connection Quit already closed.
+	defer func() {
+		_ = client.Close()
+	}()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH")
+		}
+		if err := client.Auth(n.Auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := client.Rcpt(strings.TrimSpace(to)); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
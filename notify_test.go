@@ -0,0 +1,262 @@
+package abcrss
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var notifyItem = Item{
+	Title:       "Paper cuts & <deadlines>",
+	Link:        "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201",
+	Description: "The long description of paper cuts.",
+	GUID:        GUID{IsPermaLink: true, Value: "https://www.abc.net.au/mediawatch/episodes/paper-cuts/201"},
+}
+
+func TestExecNotifier(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run")
+	}
+	out := filepath.Join(t.TempDir(), "item.json")
+	n := &ExecNotifier{Command: "sh", Args: []string{"-c", `cat > "$0"`, out}}
+	if err := n.Notify(context.Background(), notifyItem); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got JSONFeedItem
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("decoding %s: %v", b, err)
+	}
+	if got.ID != notifyItem.GUID.Value || got.URL != notifyItem.Link || got.Title != notifyItem.Title {
+		t.Errorf("got %+v", got)
+	}
+
+	failing := &ExecNotifier{Command: "sh", Args: []string{"-c", "echo broken; exit 3"}}
+	if err := failing.Notify(context.Background(), notifyItem); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Notify = %v, want an error with the command output", err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	for _, tt := range []struct {
+		style WebhookStyle
+		want  map[string]any
+	}{
+		{WebhookSlack, map[string]any{
+			"text": "New: <https://www.abc.net.au/mediawatch/episodes/paper-cuts/201|Paper cuts &amp; &lt;deadlines&gt;>",
+		}},
+		{WebhookDiscord, map[string]any{
+			"content": "New: Paper cuts & <deadlines>\nhttps://www.abc.net.au/mediawatch/episodes/paper-cuts/201",
+		}},
+		{WebhookMatrix, map[string]any{
+			"text": "New: Paper cuts & <deadlines>\nhttps://www.abc.net.au/mediawatch/episodes/paper-cuts/201",
+			"html": `New: <a href="https://www.abc.net.au/mediawatch/episodes/paper-cuts/201">Paper cuts &amp; &lt;deadlines&gt;</a>`,
+		}},
+	} {
+		t.Run(string(tt.style), func(t *testing.T) {
+			var got map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q", ct)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding payload: %v", err)
+				}
+			}))
+			defer server.Close()
+
+			n := &WebhookNotifier{URL: server.URL, Style: tt.style, Client: server.Client()}
+			if err := n.Notify(context.Background(), notifyItem); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payload = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run(string(WebhookJSON), func(t *testing.T) {
+		var got JSONFeedItem
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("decoding payload: %v", err)
+			}
+		}))
+		defer server.Close()
+
+		n := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+		if err := n.Notify(context.Background(), notifyItem); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+		if got.ID != notifyItem.GUID.Value || got.ContentText != notifyItem.Description {
+			t.Errorf("payload = %+v", got)
+		}
+	})
+}
+
+func TestWebhookNotifierStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+	if err := n.Notify(context.Background(), notifyItem); err == nil {
+		t.Error("Notify succeeded against a failing webhook")
+	}
+}
+
+func TestWebhookNotifierTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	n := &WebhookNotifier{URL: server.URL, Client: server.Client()}
+	start := time.Now()
+	if err := n.Notify(ctx, notifyItem); err == nil {
+		t.Error("Notify succeeded against a hung webhook")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify took %v", elapsed)
+	}
+}
+
+// fakeSMTP answers just enough SMTP for SMTPNotifier and sends each message's data to the
+// returned channel.
+func fakeSMTP(t *testing.T) (addr string, messages <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeBody(l) })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer closeBody(conn)
+		r := bufio.NewReader(conn)
+		reply := func(s string) {
+			_, _ = io.WriteString(conn, s+"\r\n")
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line + " ")[0]); command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				ch <- data.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTP(t)
+	n := &SMTPNotifier{Addr: addr, From: "feeds@example.com", To: []string{"me@example.com"}}
+	if err := n.Notify(context.Background(), notifyItem); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	msg := <-messages
+	for _, want := range []string{
+		"From: feeds@example.com\r\n",
+		"To: me@example.com\r\n",
+		"Subject: New: Paper cuts & <deadlines>\r\n",
+		"\r\n\r\nNew: Paper cuts & <deadlines>\r\nhttps://www.abc.net.au/mediawatch/episodes/paper-cuts/201\r\n\r\nThe long description of paper cuts.\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message does not contain %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSMTPNotifierTimeout(t *testing.T) {
+	// A server that accepts the connection but never greets.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeBody(l)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer closeBody(conn)
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	n := &SMTPNotifier{Addr: l.Addr().String(), From: "feeds@example.com", To: []string{"me@example.com"}}
+	start := time.Now()
+	if err := n.Notify(ctx, notifyItem); err == nil {
+		t.Error("Notify succeeded against a hung mail server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify took %v", elapsed)
+	}
+}
+
+func TestNotifyBoundsEachNotifier(t *testing.T) {
+	var deadlines []time.Time
+	c := NewClient(WithNotifiers(NotifierFunc(func(ctx context.Context, item Item) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Error("notifier called without a deadline")
+		}
+		deadlines = append(deadlines, deadline)
+		return nil
+	})))
+	c.notify(context.Background(), []Item{notifyItem, notifyItem})
+	if len(deadlines) != 2 {
+		t.Fatalf("notifier called %d times, want 2", len(deadlines))
+	}
+	if limit := time.Now().Add(NotifyTimeout); deadlines[0].After(limit) {
+		t.Errorf("deadline %v is more than NotifyTimeout away", deadlines[0])
+	}
+}
@@ -0,0 +1,152 @@
+package abcrss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WithBackfill makes FetchFeed follow the episode collection's pagination until the whole
+// back catalogue has been gathered. At most maxPages further pages are fetched (0 means no
+// limit) and delay is waited before each of them.
+func WithBackfill(maxPages int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.backfill = true
+		c.maxPages = maxPages
+		c.pageDelay = delay
+	}
+}
+
+// EpisodeCollection returns the props of the page's EpisodeCollection component, or nil if there is none.
+func (j *ABCJSON) EpisodeCollection() *ComponentProps {
+	components := j.Props.PageProps.Data.ComponentsContent
+	for i := range components {
+		if components[i].Component == "EpisodeCollection" {
+			return &components[i].ComponentProps
+		}
+	}
+	return nil
+}
+
+// backfillEpisodes appends the episodes from every further page of the collection to abcData.
+func (c *Client) backfillEpisodes(ctx context.Context, abcData *ABCJSON) error {
+	collection := abcData.EpisodeCollection()
+	if collection == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, item := range collection.Items {
+		seen[item.ArticleLink] = true
+	}
+
+	page := *collection
+	for fetched := 0; c.maxPages <= 0 || fetched < c.maxPages; fetched++ {
+		if page.LoadMoreURL == "" || len(collection.Items) >= page.Pagination.Total {
+			break
+		}
+		offset := page.Pagination.Offset + max(len(page.Items), page.Pagination.Size)
+		size := page.Pagination.Size
+		if size <= 0 {
+			size = len(page.Items)
+		}
+
+		if err := sleepContext(ctx, c.pageDelay); err != nil {
+			return err
+		}
+		next, err := c.fetchCollectionPage(ctx, page.LoadMoreURL, offset, size)
+		if err != nil {
+			return fmt.Errorf("fetching offset %d: %v", offset, err)
+		}
+
+		added := 0
+		for _, item := range next.Items {
+			if seen[item.ArticleLink] {
+				continue
+			}
+			seen[item.ArticleLink] = true
+			collection.Items = append(collection.Items, item)
+			added++
+		}
+		if added == 0 {
+			// Either the end of the catalogue or upstream ignored the offset; stop either way.
+			break
+		}
+
+		if next.LoadMoreURL == "" {
+			next.LoadMoreURL = page.LoadMoreURL
+		}
+		if next.Pagination.Total == 0 {
+			next.Pagination.Total = page.Pagination.Total
+		}
+		if next.Pagination.Offset == 0 {
+			next.Pagination.Offset = offset
+		}
+		page = *next
+	}
+	return nil
+}
+
+// fetchCollectionPage fetches one page of a collection from its loadMoreUrl. The response may
+// be the collection's props as JSON, or a full page with __NEXT_DATA__ embedded.
+func (c *Client) fetchCollectionPage(ctx context.Context, loadMoreURL string, offset, size int) (*ComponentProps, error) {
+	u, err := url.Parse(loadMoreURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing load more url: %v", err)
+	}
+	q := u.Query()
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("size", strconv.Itoa(size))
+	u.RawQuery = q.Encode()
+
+	body, err := c.fetcher.Fetch(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(body)
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading page: %v", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped struct {
+			ComponentProps
+			Wrapped *ComponentProps `json:"componentProps"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapped); err != nil {
+			return nil, fmt.Errorf("parsing JSON data: %v", err)
+		}
+		if wrapped.Wrapped != nil {
+			return wrapped.Wrapped, nil
+		}
+		return &wrapped.ComponentProps, nil
+	}
+
+	abcData, err := ParseNextData(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if collection := abcData.EpisodeCollection(); collection != nil {
+		return collection, nil
+	}
+	return nil, fmt.Errorf("no episode collection found")
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
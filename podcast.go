@@ -0,0 +1,123 @@
+package abcrss
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ITunesNamespace is the Apple podcast namespace URI.
+const ITunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// ITunesImage is an itunes:image element.
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// ITunesCategory is an itunes:category element.
+type ITunesCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+// Enclosure is an RSS enclosure pointing at a playable media file.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// WithPodcast adds iTunes podcast metadata to the feed, and an enclosure to every item whose
+// media file was found and sized. Media files are only found on episode pages, so combine it
+// with WithEnrichment, which then also sends a HEAD request for each file's length.
+func WithPodcast() Option {
+	return func(c *Client) {
+		c.podcast = true
+	}
+}
+
+var titleEpisodeNumber = regexp.MustCompile(`(?i)\bepisode\s+(\d+)\b`)
+
+// addPodcast fills in the iTunes channel and item elements.
+func addPodcast(rss *RSS) {
+	rss.XMLNSITunes = ITunesNamespace
+	rss.Channel.ITunesAuthor = "ABC"
+	rss.Channel.ITunesCategory = &ITunesCategory{Text: "News"}
+	rss.Channel.ITunesExplicit = "false"
+	rss.Channel.ITunesType = "episodic"
+	if rss.Channel.Artwork != "" {
+		rss.Channel.ITunesImage = &ITunesImage{Href: rss.Channel.Artwork}
+	}
+
+	for i := range rss.Channel.Items {
+		item := &rss.Channel.Items[i]
+		item.ITunesAuthor = "ABC"
+		item.ITunesEpisodeType = "full"
+		if item.Image.URL != "" {
+			item.ITunesImage = &ITunesImage{Href: item.Image.URL}
+		}
+		if item.Duration > 0 {
+			item.ITunesDuration = formatITunesDuration(item.Duration)
+		}
+		// RSS requires the enclosure's length, and podcast apps reject a zero, so an item whose
+		// file could not be sized goes without.
+		if item.MediaURL != "" && item.MediaLength > 0 {
+			item.Enclosure = &Enclosure{URL: item.MediaURL, Length: item.MediaLength, Type: firstNonEmpty(item.MediaType, mediaTypeOf(item.MediaURL))}
+		}
+	}
+	numberEpisodes(rss.Channel.Items)
+}
+
+// numberEpisodes uses the broadcast year as the season. The episode number comes from the title
+// when it has one, otherwise it is the item's position within its year, which is only accurate
+// when the feed holds the whole year, for example after a backfill.
+func numberEpisodes(items []Item) {
+	byYear := map[int][]*Item{}
+	for i := range items {
+		item := &items[i]
+		if item.Published.IsZero() {
+			continue
+		}
+		year := item.Published.Year()
+		item.ITunesSeason = year
+		byYear[year] = append(byYear[year], item)
+	}
+	for _, yearItems := range byYear {
+		sort.SliceStable(yearItems, func(i, j int) bool {
+			return yearItems[i].Published.Before(yearItems[j].Published)
+		})
+		for n, item := range yearItems {
+			if m := titleEpisodeNumber.FindStringSubmatch(item.Title); m != nil {
+				item.ITunesEpisode, _ = strconv.Atoi(m[1])
+				continue
+			}
+			item.ITunesEpisode = n + 1
+		}
+	}
+}
+
+func formatITunesDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+}
+
+// mediaTypeOf guesses a MIME type from a media URL's extension.
+func mediaTypeOf(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	switch strings.ToLower(path.Ext(u)) {
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a":
+		return "audio/x-m4a"
+	case ".m4v":
+		return "video/x-m4v"
+	case ".mov":
+		return "video/quicktime"
+	}
+	return "video/mp4"
+}
@@ -0,0 +1,250 @@
+package abcrss
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefresh is how often a hosted program is re-scraped when its config gives no interval.
+const DefaultRefresh = time.Hour
+
+// Duration is a time.Duration that reads and writes JSON as a string such as "15m".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ProgramConfig enables one program on a FeedServer.
+type ProgramConfig struct {
+	Slug    string   `json:"slug"`
+	Refresh Duration `json:"refresh"`
+	// Archive, when set, is the JSON-lines file the program's items are kept in between refreshes.
+	Archive   string   `json:"archive,omitempty"`
+	Retention Duration `json:"retention,omitempty"`
+	MaxItems  int      `json:"maxItems,omitempty"`
+}
+
+// ServerConfig is the configuration file of the feed server.
+type ServerConfig struct {
+	Listen string `json:"listen"`
+	// PublicURL is where the server is reachable from outside, used for the feeds' self links.
+	PublicURL string          `json:"publicUrl"`
+	Programs  []ProgramConfig `json:"programs"`
+	// DiagnosticFeed serves a DiagnosticFeed, instead of a 503, for a program whose first fetch failed.
+	DiagnosticFeed bool `json:"diagnosticFeed,omitempty"`
+}
+
+// LoadServerConfig reads a JSON ServerConfig from path. Each program may appear only once.
+func LoadServerConfig(path string) (ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("reading config: %v", err)
+	}
+	var config ServerConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ServerConfig{}, fmt.Errorf("parsing config: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, program := range config.Programs {
+		if !ValidProgram(program.Slug) {
+			return ServerConfig{}, fmt.Errorf("invalid program slug: %q", program.Slug)
+		}
+		if seen[program.Slug] {
+			return ServerConfig{}, fmt.Errorf("duplicate program slug: %q", program.Slug)
+		}
+		seen[program.Slug] = true
+	}
+	return config, nil
+}
+
+// cachedFeed is the last successfully rendered feed of one program.
+type cachedFeed struct {
+	config ProgramConfig
+	client *Client
+
+	mu       sync.RWMutex
+	body     []byte
+	etag     string
+	modified time.Time
+	// err is why the last refresh failed, nil when it succeeded.
+	err error
+}
+
+// refresh re-scrapes the feed, remembering whether it failed.
+func (f *cachedFeed) refresh(ctx context.Context) error {
+	err := f.update(ctx)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+	return err
+}
+
+// update replaces the cached copy, with its ETag and modification time, only when the content
+// changed, so readers polling with validators get 304 responses.
+func (f *cachedFeed) update(ctx context.Context) error {
+	rss, err := f.client.FetchFeed(ctx)
+	if err != nil {
+		return err
+	}
+	etag, err := feedETag(rss)
+	if err != nil {
+		return err
+	}
+	f.mu.RLock()
+	unchanged := etag == f.etag
+	f.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	body, err := MarshalRSS(rss)
+	if err != nil {
+		return fmt.Errorf("marshalling rss: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+	f.etag = etag
+	f.modified = time.Now()
+	return nil
+}
+
+// feedETag hashes the feed without its lastBuildDate, which changes on every build.
+func feedETag(rss RSS) (string, error) {
+	rss.Channel.LastBuildDate = ""
+	body, err := MarshalRSS(rss)
+	if err != nil {
+		return "", fmt.Errorf("marshalling rss: %v", err)
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+func (f *cachedFeed) get() ([]byte, string, time.Time, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.body, f.etag, f.modified, f.err
+}
+
+// FeedServer hosts the feeds of several programs at /feeds/{slug}.xml, each refreshed on its own schedule.
+// When a refresh fails, the last good feed keeps being served with a Warning header.
+type FeedServer struct {
+	feeds      map[string]*cachedFeed
+	diagnostic bool
+}
+
+// NewFeedServer creates a FeedServer for the programs in config. opts are applied to every program's Client.
+func NewFeedServer(config ServerConfig, opts ...Option) *FeedServer {
+	s := &FeedServer{feeds: map[string]*cachedFeed{}, diagnostic: config.DiagnosticFeed}
+	for _, program := range config.Programs {
+		if program.Refresh <= 0 {
+			program.Refresh = Duration(DefaultRefresh)
+		}
+		clientOpts := append(slices.Clone(opts), WithProgram(program.Slug), WithTTL(time.Duration(program.Refresh)))
+		if program.Archive != "" {
+			clientOpts = append(clientOpts, WithArchive(program.Archive, Retention{MaxAge: time.Duration(program.Retention), MaxItems: program.MaxItems}))
+		}
+		if config.PublicURL != "" {
+			clientOpts = append(clientOpts, WithSelfURL(strings.TrimSuffix(config.PublicURL, "/")+"/feeds/"+program.Slug+".xml"))
+		}
+		s.feeds[program.Slug] = &cachedFeed{
+			config: program,
+			client: NewClient(clientOpts...),
+		}
+	}
+	return s
+}
+
+// Run refreshes every feed straight away and then on its interval, in parallel, until ctx is done.
+func (s *FeedServer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for slug, feed := range s.feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Duration(feed.config.Refresh))
+			defer ticker.Stop()
+			for {
+				if err := feed.refresh(ctx); err != nil {
+					log.Printf("Failed to refresh %s: %v", slug, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *FeedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, "/feeds/")
+	if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		http.NotFound(w, r)
+		return
+	}
+	slug, ok := strings.CutSuffix(name, ".xml")
+	feed, found := s.feeds[slug]
+	if !ok || !found {
+		http.NotFound(w, r)
+		return
+	}
+	body, etag, modified, err := feed.get()
+	if body == nil && err != nil && s.diagnostic {
+		s.serveDiagnostic(w, slug, err)
+		return
+	}
+	if body == nil {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Feed not fetched yet", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		w.Header().Set("Warning", StaleWarning)
+	}
+	w.Header().Set("Content-Type", "application/rss+xml")
+	w.Header().Set("ETag", etag)
+	// ServeContent answers If-None-Match and If-Modified-Since with 304 Not Modified.
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+func (s *FeedServer) serveDiagnostic(w http.ResponseWriter, slug string, err error) {
+	body, err := MarshalRSS(DiagnosticFeed(slug, err, time.Now()))
+	if err != nil {
+		http.Error(w, "Failed to marshal RSS", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rss+xml")
+	w.Header().Set("Warning", DiagnosticWarning)
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,193 @@
+package abcrss
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat sorts lexically in time order, which replay relies on.
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+
+// SnapshotMeta describes a page saved by RecordTransport.
+type SnapshotMeta struct {
+	URL string `json:"url"`
+	// Method is set for requests other than GET.
+	Method     string      `json:"method,omitempty"`
+	Status     string      `json:"status"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	FetchedAt  time.Time   `json:"fetchedAt"`
+	SHA256     string      `json:"sha256"`
+	BodyFile   string      `json:"bodyFile"`
+}
+
+// RecordTransport saves every response that passes through it into Dir before handing it on.
+// Each URL gets its own subdirectory holding a body file and a metadata file per fetch.
+type RecordTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	closeBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading body for snapshot: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := t.save(req.Method, req.URL.String(), resp, body); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (t *RecordTransport) save(method, u string, resp *http.Response, body []byte) error {
+	dir := filepath.Join(t.Dir, snapshotDir(method, u))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %v", err)
+	}
+	now := time.Now().UTC()
+	name := now.Format(snapshotTimeFormat)
+	sum := sha256.Sum256(body)
+	meta := SnapshotMeta{
+		URL:        u,
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		FetchedAt:  now,
+		SHA256:     hex.EncodeToString(sum[:]),
+		BodyFile:   name + ".body",
+	}
+	if method != "" && method != http.MethodGet {
+		meta.Method = method
+	}
+	if err := os.WriteFile(filepath.Join(dir, meta.BodyFile), body, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot body: %v", err)
+	}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding snapshot metadata: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), metaJSON, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot metadata: %v", err)
+	}
+	return nil
+}
+
+// ReplayTransport answers requests from snapshots saved by RecordTransport instead of the network.
+// The most recent snapshot of each URL and method is used.
+type ReplayTransport struct {
+	Dir string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := req.URL.String()
+	meta, body, err := latestSnapshot(filepath.Join(t.Dir, snapshotDir(req.Method, u)), u)
+	if err != nil {
+		return nil, err
+	}
+	contentLength := int64(len(body))
+	if req.Method == http.MethodHead {
+		contentLength = -1
+		if n, err := strconv.ParseInt(meta.Header.Get("Content-Length"), 10, 64); err == nil {
+			contentLength = n
+		}
+	}
+	return &http.Response{
+		Status:        meta.Status,
+		StatusCode:    meta.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        meta.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: contentLength,
+		Request:       req,
+	}, nil
+}
+
+// LatestSnapshot loads the most recent snapshot of u from dir, checking its content hash.
+func LatestSnapshot(dir, u string) (SnapshotMeta, []byte, error) {
+	return latestSnapshot(filepath.Join(dir, snapshotDir(http.MethodGet, u)), u)
+}
+
+func latestSnapshot(keyDir, u string) (SnapshotMeta, []byte, error) {
+	metaFiles, err := filepath.Glob(filepath.Join(keyDir, "*.json"))
+	if err != nil {
+		return SnapshotMeta{}, nil, fmt.Errorf("listing snapshots: %v", err)
+	}
+	if len(metaFiles) == 0 {
+		return SnapshotMeta{}, nil, fmt.Errorf("no snapshot for %s", u)
+	}
+	sort.Strings(metaFiles)
+
+	metaJSON, err := os.ReadFile(metaFiles[len(metaFiles)-1])
+	if err != nil {
+		return SnapshotMeta{}, nil, fmt.Errorf("reading snapshot metadata: %v", err)
+	}
+	var meta SnapshotMeta
+	if err := json.Unmarshal(metaJSON, &meta); err != nil {
+		return SnapshotMeta{}, nil, fmt.Errorf("parsing snapshot metadata: %v", err)
+	}
+	body, err := os.ReadFile(filepath.Join(keyDir, meta.BodyFile))
+	if err != nil {
+		return SnapshotMeta{}, nil, fmt.Errorf("reading snapshot body: %v", err)
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != meta.SHA256 {
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot of %s does not match its recorded hash", u)
+	}
+	return meta, body, nil
+}
+
+var unsafeSnapshotChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// snapshotDir names the directory holding the snapshots of u fetched with method. Methods
+// other than GET, such as the HEAD requests that size podcast media, get their own directory,
+// so their empty bodies are never replayed for a GET.
+func snapshotDir(method, u string) string {
+	if method == "" || method == http.MethodGet {
+		return snapshotKey(u)
+	}
+	return strings.ToLower(method) + "-" + snapshotKey(u)
+}
+
+// snapshotKeyPrefix is how much of the readable URL a snapshot directory name keeps, leaving
+// room for the hash within the 255 byte name limit of common file systems.
+const snapshotKeyPrefix = 100
+
+// snapshotKey turns a URL into a directory name that is safe on every platform: a readable,
+// truncated form of the URL followed by a hash of all of it, so URLs differing only in
+// punctuation or past the cut do not share a directory.
+func snapshotKey(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	readable := strings.TrimPrefix(strings.TrimPrefix(u, "https://"), "http://")
+	readable = unsafeSnapshotChars.ReplaceAllString(readable, "_")
+	if len(readable) > snapshotKeyPrefix {
+		readable = readable[:snapshotKeyPrefix]
+	}
+	return strings.Trim(readable, "_.") + "-" + hex.EncodeToString(sum[:8])
+}